@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/sillsdev/appbuilder-storybuilder/src/helper"
+	"github.com/sillsdev/appbuilder-storybuilder/src/options"
+	"github.com/sillsdev/appbuilder-storybuilder/src/slideshow"
+)
+
+/* Function to fill a template with the narration audio and timings of a language
+ *
+ * Parameters:
+ *		args - the command line arguments after the command name
+ */
+func instantiate(args []string) {
+	optionFlags := options.ParseInstantiateFlags(args)
+
+	err := slideshow.Instantiate(optionFlags.TemplatePath, optionFlags.NarrationPath, optionFlags.LabelsPath, optionFlags.OutputPath, optionFlags.Verbose)
+	helper.Check(err)
+}
+
+/* Function to suggest slide timings from the pauses of a narration
+ *
+ * Parameters:
+ *		args - the command line arguments after the command name
+ */
+func suggest(args []string) {
+	optionFlags := options.ParseSuggestFlags(args)
+
+	err := slideshow.Suggest(optionFlags.TemplatePath, optionFlags.NarrationPath, optionFlags.OutputPath, optionFlags.Target, optionFlags.Noise, optionFlags.MinPause, optionFlags.Verbose)
+	helper.Check(err)
+}
+
+/* Function to verify or rebuild the timings of a .slideshow from its SAB log
+ *
+ * Parameters:
+ *		args - the command line arguments after the command name
+ */
+func sabLog(args []string) {
+	optionFlags := options.ParseSABLogFlags(args)
+
+	err := slideshow.AuditSABLog(optionFlags.SlideshowPath, optionFlags.LogPath, optionFlags.OutputPath, optionFlags.Verbose)
+	helper.Check(err)
+}
+
+/* Function to create a .slideshow from a folder of images
+ *
+ * Parameters:
+ *		args - the command line arguments after the command name
+ */
+func initSlideshow(args []string) {
+	optionFlags := options.ParseInitFlags(args)
+
+	err := slideshow.Init(optionFlags.Directory, optionFlags.AudioPath, optionFlags.Duration, optionFlags.OutputPath, optionFlags.Verbose)
+	helper.Check(err)
+}
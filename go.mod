@@ -1,3 +1,8 @@
 module github.com/sillsdev/appbuilder-storybuilder
 
 go 1.17
+
+require (
+	golang.org/x/image v0.10.0
+	golang.org/x/text v0.11.0
+)
@@ -4,10 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"time"
 
+	FFmpeg "github.com/sillsdev/appbuilder-storybuilder/src/ffmpeg"
 	"github.com/sillsdev/appbuilder-storybuilder/src/helper"
 	"github.com/sillsdev/appbuilder-storybuilder/src/options"
 	OS "github.com/sillsdev/appbuilder-storybuilder/src/os"
@@ -18,8 +22,36 @@ var filePath string
 
 // Main function
 func main() {
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "instantiate":
+			instantiate(args[1:])
+			return
+		case "suggest":
+			suggest(args[1:])
+			return
+		case "sablog":
+			sabLog(args[1:])
+			return
+		case "init":
+			initSlideshow(args[1:])
+			return
+		case "render":
+			args = args[1:]
+		}
+	}
+	render(args)
+}
+
+/* Function to render a .slideshow to a video, the default command
+ *
+ * Parameters:
+ *		args - the command line arguments after the command name
+ */
+func render(args []string) {
 	// Ask the user for options
-	optionFlags := options.ParseFlags()
+	optionFlags := options.ParseFlags(args)
 
 	// Create a temporary folder to store temporary files
 	tempDirectory, err := OS.CreateDirectory(optionFlags.TemporaryDirectory, optionFlags.Verbose)
@@ -45,17 +77,97 @@ func main() {
 	start := time.Now()
 
 	// Parse in the various pieces from the template
-	slideshow := slideshow.NewSlideshow(optionFlags.SlideshowDirectory, optionFlags.Verbose, tempDirectory)
+	slideshow := slideshow.NewSlideshow(optionFlags.SlideshowDirectory, optionFlags.Lang, optionFlags.Verbose, tempDirectory)
+
+	// Previews, images for catalogues, etc. are stored next to the final video, named after it
+	outputName, err := slideshow.OutputName(optionFlags.OutputTemplate, optionFlags.LowQuality)
+	helper.Check(err)
+	outputBase := path.Join(optionFlags.OutputDirectory, strings.TrimSuffix(outputName, ".mp4"))
+	err = os.MkdirAll(path.Dir(outputBase), os.ModePerm)
+	helper.Check(err)
+
+	var ducking *FFmpeg.Ducking
+	if !optionFlags.NoDucking {
+		ducking = &FFmpeg.Ducking{Threshold: optionFlags.DuckThreshold, Ratio: optionFlags.DuckRatio, Attack: optionFlags.DuckAttack, Release: optionFlags.DuckRelease}
+	}
+	var loudness *FFmpeg.LoudnessTarget
+	if optionFlags.Loudnorm {
+		loudness = &FFmpeg.LoudnessTarget{Integrated: optionFlags.LoudnessTarget, TruePeak: optionFlags.TruePeak, Range: optionFlags.LoudnessRange}
+	}
+	fades := FFmpeg.AudioFades{MusicIn: optionFlags.MusicFadeIn / 1000, MusicOut: optionFlags.MusicFadeOut / 1000,
+		Click: optionFlags.ClickFade / 1000, Crossfade: optionFlags.AudioCrossfade}
+	err = slideshow.ExtractNarration(optionFlags.Verbose)
+	helper.Check(err)
+	slideshow.MixMusic(ducking, fades, optionFlags.Verbose)
+
+	if optionFlags.AudioOnly != "" {
+		fmt.Println("-audioonly specified, exporting audio and timeline instead of the video...")
+		err := slideshow.ExportAudioOnly(optionFlags.AudioOnly, fades, loudness, outputBase, optionFlags.Verbose)
+		helper.Check(err)
+		finish(optionFlags.SaveTemps, tempDirectory)
+		return
+	}
+
+	if optionFlags.RenderCards {
+		fmt.Println("Rendering title and credits cards...")
+		credits := ""
+		if optionFlags.CreditsFile != "" {
+			data, err := os.ReadFile(optionFlags.CreditsFile)
+			helper.Check(err)
+			credits = string(data)
+		}
+		err = slideshow.RenderCards(optionFlags.CardFonts, optionFlags.CardBackground, optionFlags.CardLayout, credits, optionFlags.Verbose)
+		helper.Check(err)
+	}
+
+	if optionFlags.Motion != "" {
+		fmt.Println("Generating motions...")
+		err := slideshow.GenerateMotions(optionFlags.Motion, optionFlags.MotionSeed, optionFlags.Saliency, optionFlags.Verbose)
+		helper.Check(err)
+	}
 
 	fmt.Println("Scaling images...")
 	slideshow.ScaleImages(optionFlags.LowQuality, optionFlags.Verbose)
 
+	metadata, err := slideshow.Metadata(optionFlags.MetadataFile, optionFlags.LowQuality)
+	helper.Check(err)
+
+	if optionFlags.Preview != "" {
+		fmt.Println("-preview specified, creating preview instead of the full video...")
+		err := slideshow.CreatePreview(optionFlags.Preview, optionFlags.Renderer, tempDirectory, outputBase, optionFlags.Verbose)
+		helper.Check(err)
+		finish(optionFlags.SaveTemps, tempDirectory)
+		return
+	}
+
 	fmt.Println("Creating video...")
-	slideshow.CreateVideo(optionFlags.UseOldFade, tempDirectory, optionFlags.OutputDirectory, optionFlags.Verbose)
+	offsets, measured := slideshow.CreateVideo(optionFlags.UseOldFade, tempDirectory, optionFlags.OutputDirectory, outputName, metadata, optionFlags.Chapters, loudness, fades, optionFlags.Renderer, optionFlags.Verbose)
+
+	if optionFlags.Poster != "" {
+		err := slideshow.CreatePoster(optionFlags.Poster, offsets, path.Join(tempDirectory, "final.mp4"), outputBase+"-poster.jpg")
+		helper.Check(err)
+	}
+	if optionFlags.HLS || optionFlags.DASH {
+		slideshow.CreateStreams(path.Join(tempDirectory, "final.mp4"), optionFlags.Subtitles, optionFlags.Renditions, optionFlags.LowQuality,
+			optionFlags.HLS, optionFlags.DASH, outputBase)
+	}
+	if optionFlags.Thumbnails {
+		err := slideshow.CreateThumbnails(outputBase + "-thumbs")
+		helper.Check(err)
+	}
+	if optionFlags.ContactSheet {
+		err := slideshow.CreateContactSheet(4, outputBase+"-contact.jpg")
+		helper.Check(err)
+	}
 
 	fmt.Println("Video production completed!")
 	duration := time.Since(start)
-	fmt.Sprintln(fmt.Sprintf("Time Taken: %f seconds", duration.Seconds()))
+	fmt.Printf("Time Taken: %f seconds\n", duration.Seconds())
+
+	if optionFlags.Report != "" {
+		err := slideshow.WriteReport(optionFlags.Report, path.Join(optionFlags.OutputDirectory, outputName), offsets, measured, duration.Seconds())
+		helper.Check(err)
+	}
 
 	if optionFlags.OverlayVideoDirectory != "" {
 		fmt.Println("-ov specified, creating overlay video with ", optionFlags.OverlayVideoDirectory)
@@ -66,12 +178,21 @@ func main() {
 		fmt.Println("Finished creating overlay video")
 	}
 
+	finish(optionFlags.SaveTemps, tempDirectory)
+}
+
+/* Function to clean up after the video production
+ *
+ * Parameters:
+ *		saveTemps - whether the user specified the -s flag to keep the temporary files
+ *		tempDirectory - the path to the temporary directory
+ */
+func finish(saveTemps bool, tempDirectory string) {
 	// If user did not specify the -s flag at runtime, delete all the temporary videos
-	if !(optionFlags.SaveTemps) {
+	if !saveTemps {
 		err := OS.DeleteTemporaryDirectory(tempDirectory)
 		helper.Check(err)
 	}
-
 }
 
 /* Function to search the current directory for any .slideshow files and return the first found
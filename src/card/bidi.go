@@ -0,0 +1,133 @@
+package card
+
+import (
+	"golang.org/x/text/unicode/bidi"
+)
+
+/* Structure of a run of characters that share a direction
+ *	runes: the characters of the run in logical order
+ *	rtl: whether the run is written right to left
+ */
+type run struct {
+	runes []rune
+	rtl   bool
+}
+
+/* Function to reorder a line from logical to visual order so right-to-left scripts
+ * (Arabic, Hebrew, ...) draw correctly. The paragraph direction comes from the first
+ * strong character; numbers and Latin words embedded in RTL text keep their order.
+ * Arabic letters are joined beforehand by shapeArabic.
+ *
+ * Parameters:
+ *		line - the text in logical order
+ * Returns:
+ *		the text in visual (left to right drawing) order
+ */
+func visualOrder(line string) string {
+	runes := []rune(line)
+	rtl := make([]bool, len(runes))
+	strong := make([]bool, len(runes))
+	baseRTL := false
+	foundStrong := false
+	for i, r := range runes {
+		rtl[i], strong[i] = direction(r)
+		if strong[i] && !foundStrong {
+			baseRTL = rtl[i]
+			foundStrong = true
+		}
+	}
+
+	// Neutral characters between two strong characters of the same direction take that direction,
+	// the others take the paragraph direction (rules N1 and N2 of the Unicode bidi algorithm)
+	for i := 0; i < len(runes); i++ {
+		if strong[i] {
+			continue
+		}
+		end := i
+		for end < len(runes) && !strong[end] {
+			end++
+		}
+		before, after := baseRTL, baseRTL
+		if i > 0 {
+			before = rtl[i-1]
+		}
+		if end < len(runes) {
+			after = rtl[end]
+		}
+		resolved := baseRTL
+		if before == after {
+			resolved = before
+		}
+		for j := i; j < end; j++ {
+			rtl[j] = resolved
+		}
+		i = end - 1
+	}
+
+	runs := []run{}
+	for i, r := range runes {
+		if len(runs) == 0 || runs[len(runs)-1].rtl != rtl[i] {
+			runs = append(runs, run{rtl: rtl[i]})
+		}
+		runs[len(runs)-1].runes = append(runs[len(runs)-1].runes, r)
+	}
+
+	if !foundStrong {
+		return line
+	}
+
+	if baseRTL {
+		for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+			runs[i], runs[j] = runs[j], runs[i]
+		}
+	}
+
+	visual := []rune{}
+	for _, r := range runs {
+		if r.rtl {
+			for i := len(r.runes) - 1; i >= 0; i-- {
+				visual = append(visual, mirror(r.runes[i]))
+			}
+		} else {
+			visual = append(visual, r.runes...)
+		}
+	}
+	return string(visual)
+}
+
+/* Function to find the direction of a character
+ *
+ * Parameters:
+ *		r - the character
+ * Returns:
+ *		rtl - true if the character is right to left
+ *		strong - true if the character has a strong direction (letters and digits)
+ */
+func direction(r rune) (bool, bool) {
+	props, _ := bidi.LookupRune(r)
+	switch props.Class() {
+	case bidi.R, bidi.AL:
+		return true, true
+	case bidi.L, bidi.EN, bidi.AN:
+		return false, true
+	}
+	return false, false
+}
+
+func mirror(r rune) rune {
+	switch r {
+	case '(':
+		return ')'
+	case ')':
+		return '('
+	case '[':
+		return ']'
+	case ']':
+		return '['
+	case '<':
+		return '>'
+	case '>':
+		return '<'
+	}
+	return r
+}
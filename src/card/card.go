@@ -0,0 +1,362 @@
+package card
+
+import (
+	"errors"
+	"fmt"
+	Image "image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+/* Structure of a card layout template
+ *	fontScale: height of the text as a fraction of the card height
+ *	maxWidth: widest a line of text can be as a fraction of the card width
+ *	top: vertical position of the text block as a fraction of the card height, negative to center the block
+ *	lineSpacing: distance between baselines as a multiple of the text height
+ */
+type layout struct {
+	fontScale   float64
+	maxWidth    float64
+	top         float64
+	lineSpacing float64
+}
+
+// Layout templates that can be chosen for the generated cards
+var layouts = map[string]layout{
+	"centered":    {fontScale: 0.09, maxWidth: 0.8, top: -1, lineSpacing: 1.4},
+	"lower-third": {fontScale: 0.07, maxWidth: 0.9, top: 0.7, lineSpacing: 1.3},
+	"credits":     {fontScale: 0.045, maxWidth: 0.85, top: -1, lineSpacing: 1.5},
+}
+
+// Layout templates that can be chosen for the title card, the credits card always uses "credits"
+var titleLayouts = []string{"centered", "lower-third"}
+
+/* Function to check whether a layout template can be chosen for the title card
+ *
+ * Parameters:
+ *		name - name of the layout template
+ * Returns:
+ *		true if the layout is one of the title layouts
+ */
+func IsTitleLayout(name string) bool {
+	for _, layout := range titleLayouts {
+		if name == layout {
+			return true
+		}
+	}
+	return false
+}
+
+/* Function to render a title or credits card to a JPEG image
+ *
+ * Parameters:
+ *		text - the text to draw, lines are separated by newlines
+ *		fontPaths - TTF/OTF fonts to use, glyphs missing from the first font are looked up in the next ones (bundled Go font if empty)
+ *		background - path to a background image or a "#rrggbb" colour (black if empty)
+ *		layoutName - name of the layout template to use
+ *		width - pixel width of the card
+ *		height - pixel height of the card
+ *		outputPath - location to write the JPEG image
+ * Returns:
+ *		err - error in the event of a failure, nil if successful
+ */
+func Render(text string, fontPaths []string, background string, layoutName string, width int, height int, outputPath string) error {
+	l, ok := layouts[layoutName]
+	if !ok {
+		return fmt.Errorf("unknown card layout %q", layoutName)
+	}
+
+	img := Image.NewRGBA(Image.Rect(0, 0, width, height))
+	average, err := drawBackground(img, background)
+	if err != nil {
+		return err
+	}
+
+	fonts, err := loadFonts(fontPaths)
+	if err != nil {
+		return err
+	}
+
+	size, faces, lines, err := fitText(text, fonts, l, width, height)
+	if err != nil {
+		return err
+	}
+
+	textColor, shadowColor := contrastColors(average)
+	lineHeight := size * l.lineSpacing
+	blockHeight := lineHeight * float64(len(lines))
+	top := l.top * float64(height)
+	if l.top < 0 {
+		top = (float64(height) - blockHeight) / 2
+	}
+
+	for i, line := range lines {
+		visual := visualOrder(line)
+		advance := measure(visual, faces)
+		x := fixed.I(width)/2 - advance/2
+		y := fixed.Int26_6((top + lineHeight*float64(i) + size) * 64)
+		offset := fixed.I(int(size/24) + 1)
+		drawLine(img, visual, faces, shadowColor, fixed.Point26_6{X: x + offset, Y: y + offset})
+		drawLine(img, visual, faces, textColor, fixed.Point26_6{X: x, Y: y})
+	}
+
+	fd, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	return jpeg.Encode(fd, img, &jpeg.Options{Quality: 95})
+}
+
+/* Function to find the largest text size, down to 8 pixels, at which the wrapped text fits in the card
+ *
+ * Parameters:
+ *		text - the text to draw, lines are separated by newlines
+ *		fonts - the fonts in fallback order
+ *		l - the layout template
+ *		width - pixel width of the card
+ *		height - pixel height of the card
+ * Returns:
+ *		the text size, the faces of that size and the lines wrapped with them, and an error if a face cannot be created
+ */
+func fitText(text string, fonts []*opentype.Font, l layout, width int, height int) (float64, []font.Face, []string, error) {
+	size := l.fontScale * float64(height)
+	// Shrink the text until the wrapped block fits in the card, the faces and lines are always of the same size
+	for {
+		faces, err := newFaces(fonts, size)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		lines := wrapText(shapeArabic(text, faces), faces, fixed.I(int(l.maxWidth*float64(width))))
+		if float64(len(lines))*size*l.lineSpacing <= 0.9*float64(height) || size*0.9 <= 8 {
+			return size, faces, lines, nil
+		}
+		size *= 0.9
+	}
+}
+
+/* Function to fill the card with the background image (scaled to cover the card) or colour
+ *
+ * Parameters:
+ *		img - the card to draw on
+ *		background - path to an image or a "#rrggbb" colour
+ * Returns:
+ *		average - approximate average colour of the background, used to pick a readable text colour
+ *		err - error in the event of a failure, nil if successful
+ */
+func drawBackground(img *Image.RGBA, background string) (color.RGBA, error) {
+	if background == "" {
+		background = "#000000"
+	}
+
+	if strings.HasPrefix(background, "#") {
+		c, err := parseColor(background)
+		if err != nil {
+			return c, err
+		}
+		draw.Draw(img, img.Bounds(), &Image.Uniform{c}, Image.Point{}, draw.Src)
+		return c, nil
+	}
+
+	fd, err := os.Open(background)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+	defer fd.Close()
+
+	src, _, err := Image.Decode(fd)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+
+	// Crop the background to the card's aspect ratio so it covers the card without stretching
+	srcBounds := src.Bounds()
+	crop := srcBounds
+	cardRatio := float64(img.Bounds().Dx()) / float64(img.Bounds().Dy())
+	if float64(srcBounds.Dx())/float64(srcBounds.Dy()) > cardRatio {
+		w := int(float64(srcBounds.Dy()) * cardRatio)
+		crop.Min.X += (srcBounds.Dx() - w) / 2
+		crop.Max.X = crop.Min.X + w
+	} else {
+		h := int(float64(srcBounds.Dx()) / cardRatio)
+		crop.Min.Y += (srcBounds.Dy() - h) / 2
+		crop.Max.Y = crop.Min.Y + h
+	}
+	draw.CatmullRom.Scale(img, img.Bounds(), src, crop, draw.Src, nil)
+
+	return averageColor(img), nil
+}
+
+/* Function to parse a "#rrggbb" colour
+ *
+ * Parameters:
+ *		hex - the colour string
+ * Returns:
+ *		the parsed colour and an error if the string is malformed
+ */
+func parseColor(hex string) (color.RGBA, error) {
+	value, err := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 32)
+	if err != nil || len(hex) != 7 {
+		return color.RGBA{}, fmt.Errorf("invalid colour %q, expected #rrggbb", hex)
+	}
+	return color.RGBA{uint8(value >> 16), uint8(value >> 8), uint8(value), 255}, nil
+}
+
+func averageColor(img *Image.RGBA) color.RGBA {
+	var r, g, b, n int
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += 8 {
+		for x := bounds.Min.X; x < bounds.Max.X; x += 8 {
+			c := img.RGBAAt(x, y)
+			r += int(c.R)
+			g += int(c.G)
+			b += int(c.B)
+			n++
+		}
+	}
+	return color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), 255}
+}
+
+/* Function to pick the text and shadow colours that stand out on a background
+ *
+ * Parameters:
+ *		background - average colour of the background
+ * Returns:
+ *		the text colour and the shadow colour
+ */
+func contrastColors(background color.RGBA) (color.Color, color.Color) {
+	luma := 0.299*float64(background.R) + 0.587*float64(background.G) + 0.114*float64(background.B)
+	if luma > 150 {
+		return color.Black, color.NRGBA{255, 255, 255, 160}
+	}
+	return color.White, color.NRGBA{0, 0, 0, 160}
+}
+
+func loadFonts(fontPaths []string) ([]*opentype.Font, error) {
+	fonts := []*opentype.Font{}
+	for _, fontPath := range fontPaths {
+		if fontPath == "" {
+			continue
+		}
+		data, err := os.ReadFile(fontPath)
+		if err != nil {
+			return nil, err
+		}
+		f, err := opentype.Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fontPath, err)
+		}
+		fonts = append(fonts, f)
+	}
+
+	// The bundled font is always the last fallback
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return nil, err
+	}
+	return append(fonts, f), nil
+}
+
+func newFaces(fonts []*opentype.Font, size float64) ([]font.Face, error) {
+	faces := []font.Face{}
+	for _, f := range fonts {
+		face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+		if err != nil {
+			return nil, err
+		}
+		faces = append(faces, face)
+	}
+	if len(faces) == 0 {
+		return nil, errors.New("no fonts available")
+	}
+	return faces, nil
+}
+
+/* Function to find the first face that has a glyph for a rune
+ *
+ * Parameters:
+ *		r - the rune to look up
+ *		faces - the faces in fallback order
+ * Returns:
+ *		the face to draw the rune with
+ */
+func faceFor(r rune, faces []font.Face) font.Face {
+	for _, face := range faces {
+		if _, ok := face.GlyphAdvance(r); ok {
+			return face
+		}
+	}
+	return faces[0]
+}
+
+func measure(line string, faces []font.Face) fixed.Int26_6 {
+	var advance fixed.Int26_6
+	for _, r := range line {
+		a, _ := faceFor(r, faces).GlyphAdvance(r)
+		advance += a
+	}
+	return advance
+}
+
+func drawLine(img draw.Image, line string, faces []font.Face, c color.Color, dot fixed.Point26_6) {
+	for _, r := range line {
+		face := faceFor(r, faces)
+		d := &font.Drawer{Dst: img, Src: Image.NewUniform(c), Face: face, Dot: dot}
+		d.DrawString(string(r))
+		dot = d.Dot
+	}
+}
+
+/* Function to wrap text into lines that fit within a width. Text without spaces
+ * (e.g. Chinese or Thai) is broken between characters.
+ *
+ * Parameters:
+ *		text - the text to wrap, existing newlines are kept
+ *		faces - the faces used to measure the text
+ *		maxWidth - the widest a line can be
+ * Returns:
+ *		the wrapped lines
+ */
+func wrapText(text string, faces []font.Face, maxWidth fixed.Int26_6) []string {
+	lines := []string{}
+	for _, paragraph := range strings.Split(strings.TrimSpace(text), "\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			lines = append(lines, "")
+			continue
+		}
+
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := strings.TrimSpace(line + " " + word)
+			if measure(candidate, faces) <= maxWidth {
+				line = candidate
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			line = ""
+			for _, r := range word {
+				if line != "" && measure(line+string(r), faces) > maxWidth {
+					lines = append(lines, line)
+					line = ""
+				}
+				line += string(r)
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,157 @@
+package card
+
+import (
+	"image/jpeg"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// A face with a glyph for every character
+type allGlyphs struct {
+	font.Face
+}
+
+func (allGlyphs) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	return fixed.I(10), true
+}
+
+func TestShapeArabic(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"initial, lam-alef and isolated", "سلام", "\uFEB3\uFEFC\uFEE1"},
+		{"initial, medial and final", "بيت", "\uFE91\uFEF4\uFE96"},
+		{"letters that only join the previous one", "دار", "\uFEA9\uFE8D\uFEAD"},
+		{"marks do not break the joining", "بَيت", "\uFE91\u064E\uFEF4\uFE96"},
+		{"words are shaped separately", "بيت بيت", "\uFE91\uFEF4\uFE96 \uFE91\uFEF4\uFE96"},
+		{"Persian letters", "پک", "\uFB58\uFB8F"},
+		{"other scripts are kept", "The Word", "The Word"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shapeArabic(tt.text, []font.Face{allGlyphs{}}); got != tt.want {
+				t.Errorf("shapeArabic(%q) = %U, want %U", tt.text, []rune(got), []rune(tt.want))
+			}
+		})
+	}
+
+	// Without presentation forms in the fonts, the letters are kept
+	faces, err := newFaces(mustLoadFonts(t), 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := shapeArabic("سلام", faces); got != "سلام" {
+		t.Errorf("shapeArabic() without Arabic fonts = %U, want the letters unchanged", []rune(got))
+	}
+}
+
+func mustLoadFonts(t *testing.T) []*opentype.Font {
+	fonts, err := loadFonts(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fonts
+}
+
+func TestVisualOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"left to right", "The Word", "The Word"},
+		{"Hebrew", "שלום", "םולש"},
+		{"Hebrew in an English line", "Hello שלום", "Hello םולש"},
+		{"English in a Hebrew line", "שלום world", "world םולש"},
+		{"numbers keep their order", "يوحنا 1:1", "1:1 انحوي"},
+		{"brackets are mirrored", "(שלום)", "(םולש)"},
+		{"no strong characters", "1:1", "1:1"},
+		{"spaces between runs", "Hello שלום עולם world", "Hello םלוע םולש world"},
+		{"English words in a Hebrew line", "א ABC DEF ב", "ב ABC DEF א"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := visualOrder(tt.line); got != tt.want {
+				t.Errorf("visualOrder(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	faces, err := newFaces(mustLoadFonts(t), 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		text  string
+		width string
+		want  []string
+	}{
+		{"fits on one line", "In the beginning", "In the beginning", []string{"In the beginning"}},
+		{"wraps between words", "In the beginning was the Word", "In the beginning", []string{"In the beginning", "was the Word"}},
+		{"keeps the newlines", "The Word\n\nJohn 1", "The Word", []string{"The Word", "", "John 1"}},
+		{"breaks text without spaces between characters", "太初有道道與神同在", "太初有道", []string{"太初有道", "道與神同", "在"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrapText(tt.text, faces, measure(tt.width, faces))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrapText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFitText(t *testing.T) {
+	fonts := mustLoadFonts(t)
+	l := layouts["centered"]
+	text := strings.Repeat("In the beginning was the Word, and the Word was with God. ", 40)
+	size, faces, lines, err := fitText(text, fonts, l, 320, 180)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size > 8/0.9 {
+		t.Errorf("fitText() size = %v, want the smallest size for text that does not fit", size)
+	}
+	// The lines are wrapped with the faces they are drawn with
+	if want := wrapText(text, faces, fixed.I(int(l.maxWidth*320))); !reflect.DeepEqual(lines, want) {
+		t.Errorf("fitText() lines were not wrapped at size %v", size)
+	}
+}
+
+func TestRender(t *testing.T) {
+	output := path.Join(t.TempDir(), "title.jpg")
+	if err := Render("The Word", nil, "#204060", "lower-third", 320, 180, output); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	file, err := os.Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	config, err := jpeg.DecodeConfig(file)
+	if err != nil || config.Width != 320 || config.Height != 180 {
+		t.Errorf("Render() wrote a %dx%d image, %v, want 320x180", config.Width, config.Height, err)
+	}
+
+	if err := Render("The Word", nil, "", "upper-third", 320, 180, output); err == nil {
+		t.Errorf("Render() with an unknown layout should fail")
+	}
+	if err := Render("The Word", nil, "#2040", "centered", 320, 180, output); err == nil {
+		t.Errorf("Render() with an invalid background colour should fail")
+	}
+	if IsTitleLayout("upper-third") || IsTitleLayout("credits") || !IsTitleLayout("centered") || !IsTitleLayout("lower-third") {
+		t.Errorf("IsTitleLayout() does not match the title layouts")
+	}
+}
@@ -0,0 +1,197 @@
+package card
+
+import (
+	"golang.org/x/image/font"
+)
+
+/* Presentation forms of the Arabic letters: isolated, final, initial and medial.
+ * Letters that only join to the previous letter (e.g. alef, dal, reh, waw) have no initial or medial form.
+ */
+var arabicForms = map[rune][4]rune{
+	0x0621: {0xFE80, 0, 0, 0},                // hamza
+	0x0622: {0xFE81, 0xFE82, 0, 0},           // alef with madda above
+	0x0623: {0xFE83, 0xFE84, 0, 0},           // alef with hamza above
+	0x0624: {0xFE85, 0xFE86, 0, 0},           // waw with hamza above
+	0x0625: {0xFE87, 0xFE88, 0, 0},           // alef with hamza below
+	0x0626: {0xFE89, 0xFE8A, 0xFE8B, 0xFE8C}, // yeh with hamza above
+	0x0627: {0xFE8D, 0xFE8E, 0, 0},           // alef
+	0x0628: {0xFE8F, 0xFE90, 0xFE91, 0xFE92}, // beh
+	0x0629: {0xFE93, 0xFE94, 0, 0},           // teh marbuta
+	0x062A: {0xFE95, 0xFE96, 0xFE97, 0xFE98}, // teh
+	0x062B: {0xFE99, 0xFE9A, 0xFE9B, 0xFE9C}, // theh
+	0x062C: {0xFE9D, 0xFE9E, 0xFE9F, 0xFEA0}, // jeem
+	0x062D: {0xFEA1, 0xFEA2, 0xFEA3, 0xFEA4}, // hah
+	0x062E: {0xFEA5, 0xFEA6, 0xFEA7, 0xFEA8}, // khah
+	0x062F: {0xFEA9, 0xFEAA, 0, 0},           // dal
+	0x0630: {0xFEAB, 0xFEAC, 0, 0},           // thal
+	0x0631: {0xFEAD, 0xFEAE, 0, 0},           // reh
+	0x0632: {0xFEAF, 0xFEB0, 0, 0},           // zain
+	0x0633: {0xFEB1, 0xFEB2, 0xFEB3, 0xFEB4}, // seen
+	0x0634: {0xFEB5, 0xFEB6, 0xFEB7, 0xFEB8}, // sheen
+	0x0635: {0xFEB9, 0xFEBA, 0xFEBB, 0xFEBC}, // sad
+	0x0636: {0xFEBD, 0xFEBE, 0xFEBF, 0xFEC0}, // dad
+	0x0637: {0xFEC1, 0xFEC2, 0xFEC3, 0xFEC4}, // tah
+	0x0638: {0xFEC5, 0xFEC6, 0xFEC7, 0xFEC8}, // zah
+	0x0639: {0xFEC9, 0xFECA, 0xFECB, 0xFECC}, // ain
+	0x063A: {0xFECD, 0xFECE, 0xFECF, 0xFED0}, // ghain
+	0x0640: {0x0640, 0x0640, 0x0640, 0x0640}, // tatweel
+	0x0641: {0xFED1, 0xFED2, 0xFED3, 0xFED4}, // feh
+	0x0642: {0xFED5, 0xFED6, 0xFED7, 0xFED8}, // qaf
+	0x0643: {0xFED9, 0xFEDA, 0xFEDB, 0xFEDC}, // kaf
+	0x0644: {0xFEDD, 0xFEDE, 0xFEDF, 0xFEE0}, // lam
+	0x0645: {0xFEE1, 0xFEE2, 0xFEE3, 0xFEE4}, // meem
+	0x0646: {0xFEE5, 0xFEE6, 0xFEE7, 0xFEE8}, // noon
+	0x0647: {0xFEE9, 0xFEEA, 0xFEEB, 0xFEEC}, // heh
+	0x0648: {0xFEED, 0xFEEE, 0, 0},           // waw
+	0x0649: {0xFEEF, 0xFEF0, 0, 0},           // alef maksura
+	0x064A: {0xFEF1, 0xFEF2, 0xFEF3, 0xFEF4}, // yeh
+	0x067E: {0xFB56, 0xFB57, 0xFB58, 0xFB59}, // peh
+	0x0686: {0xFB7A, 0xFB7B, 0xFB7C, 0xFB7D}, // tcheh
+	0x0698: {0xFB8A, 0xFB8B, 0, 0},           // jeh
+	0x06A9: {0xFB8E, 0xFB8F, 0xFB90, 0xFB91}, // keheh
+	0x06AF: {0xFB92, 0xFB93, 0xFB94, 0xFB95}, // gaf
+	0x06CC: {0xFBFC, 0xFBFD, 0xFBFE, 0xFBFF}, // farsi yeh
+}
+
+// Isolated and final forms of the ligatures of lam followed by an alef
+var lamAlefForms = map[rune][2]rune{
+	0x0622: {0xFEF5, 0xFEF6},
+	0x0623: {0xFEF7, 0xFEF8},
+	0x0625: {0xFEF9, 0xFEFA},
+	0x0627: {0xFEFB, 0xFEFC},
+}
+
+// Indexes of the forms in arabicForms
+const (
+	isolatedForm = iota
+	finalForm
+	initialForm
+	medialForm
+)
+
+/* Function to check whether a character is a mark (harakat) that letters join across
+ *
+ * Parameters:
+ *		r - the character
+ * Returns:
+ *		true if the character does not break the joining of the letters around it
+ */
+func isTransparent(r rune) bool {
+	return (r >= 0x064B && r <= 0x065F) || r == 0x0670
+}
+
+/* Function to check whether a letter joins to the letter after it
+ *
+ * Parameters:
+ *		r - the letter
+ * Returns:
+ *		true if the letter has initial and medial forms
+ */
+func joinsNext(r rune) bool {
+	forms, found := arabicForms[r]
+	return found && forms[initialForm] != 0
+}
+
+/* Function to check whether a letter joins to the letter before it
+ *
+ * Parameters:
+ *		r - the letter
+ * Returns:
+ *		true if the letter has a final form
+ */
+func joinsPrevious(r rune) bool {
+	forms, found := arabicForms[r]
+	return found && forms[finalForm] != 0
+}
+
+/* Function to find the nearest letter before or after a position, skipping marks
+ *
+ * Parameters:
+ *		runes - the text
+ *		i - the position
+ *		step - -1 to look before the position, 1 to look after it
+ * Returns:
+ *		the letter, or 0 at the start or end of the text
+ */
+func neighbour(runes []rune, i int, step int) rune {
+	for j := i + step; j >= 0 && j < len(runes); j += step {
+		if !isTransparent(runes[j]) {
+			return runes[j]
+		}
+	}
+	return 0
+}
+
+/* Function to shape Arabic text by replacing its letters with their contextual presentation forms
+ * (isolated, initial, medial or final, and the lam-alef ligatures), so letters drawn one glyph at a
+ * time join up. A form is only used if one of the fonts has a glyph for it.
+ *
+ * Parameters:
+ *		text - the text in logical order
+ *		faces - the faces the text is drawn with
+ * Returns:
+ *		the shaped text, still in logical order
+ */
+func shapeArabic(text string, faces []font.Face) string {
+	runes := []rune(text)
+	shaped := []rune{}
+	use := func(form rune, fallback ...rune) {
+		if form != 0 && hasGlyph(form, faces) {
+			shaped = append(shaped, form)
+		} else {
+			shaped = append(shaped, fallback...)
+		}
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		forms, found := arabicForms[r]
+		if !found {
+			shaped = append(shaped, r)
+			continue
+		}
+		joinedPrevious := joinsPrevious(r) && joinsNext(neighbour(runes, i, -1))
+
+		if r == 0x0644 && i+1 < len(runes) {
+			if ligature, found := lamAlefForms[runes[i+1]]; found {
+				if joinedPrevious {
+					use(ligature[1], r, runes[i+1])
+				} else {
+					use(ligature[0], r, runes[i+1])
+				}
+				i++
+				continue
+			}
+		}
+
+		joinedNext := joinsNext(r) && joinsPrevious(neighbour(runes, i, 1))
+		switch {
+		case joinedPrevious && joinedNext:
+			use(forms[medialForm], r)
+		case joinedPrevious:
+			use(forms[finalForm], r)
+		case joinedNext:
+			use(forms[initialForm], r)
+		default:
+			use(forms[isolatedForm], r)
+		}
+	}
+	return string(shaped)
+}
+
+/* Function to check whether any of the faces has a glyph for a character
+ *
+ * Parameters:
+ *		r - the character
+ *		faces - the faces in fallback order
+ * Returns:
+ *		true if the character can be drawn
+ */
+func hasGlyph(r rune, faces []font.Face) bool {
+	for _, face := range faces {
+		if _, ok := face.GlyphAdvance(r); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,342 @@
+package ffmpeg_pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/sillsdev/appbuilder-storybuilder/src/helper"
+)
+
+/* Structure of an EBU R128 loudness target
+ *	Integrated: integrated loudness in LUFS
+ *	TruePeak: maximum true peak in dBTP
+ *	Range: loudness range in LU
+ */
+type LoudnessTarget struct {
+	Integrated float64
+	TruePeak   float64
+	Range      float64
+}
+
+/* Structure of the values measured by the loudnorm filter (see its print_format=json output)
+ *	Input*: loudness of the mix before normalization
+ *	Output*: loudness of the mix after normalization
+ *	TargetOffset: gain offset applied to reach the target
+ */
+type LoudnessMeasurement struct {
+	InputI       string `json:"input_i"`
+	InputTP      string `json:"input_tp"`
+	InputLRA     string `json:"input_lra"`
+	InputThresh  string `json:"input_thresh"`
+	OutputI      string `json:"output_i"`
+	OutputTP     string `json:"output_tp"`
+	OutputLRA    string `json:"output_lra"`
+	OutputThresh string `json:"output_thresh"`
+	TargetOffset string `json:"target_offset"`
+}
+
+/* Function to parse the JSON block printed by the loudnorm filter at the end of the ffmpeg output
+ *
+ * Parameters:
+ *		output - the combined ffmpeg output
+ * Returns:
+ *		the measured values and an error if no measurement is found
+ */
+func parseLoudnorm(output string) (LoudnessMeasurement, error) {
+	measurement := LoudnessMeasurement{}
+	start := strings.LastIndex(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return measurement, errors.New("no loudnorm measurement found in ffmpeg output")
+	}
+
+	err := json.Unmarshal([]byte(output[start:end+1]), &measurement)
+	return measurement, err
+}
+
+/* Function to measure the loudness of the audio of a file (first loudnorm pass)
+ *
+ * Parameters:
+ *		inputPath - directory of the audio or video file
+ *		target - the loudness target
+ * Returns:
+ *		exectauble ffmpeg cmd
+ */
+func CmdMeasureLoudness(inputPath string, target LoudnessTarget) *exec.Cmd {
+	cmd := exec.Command("ffmpeg", "-hide_banner", "-i", inputPath, "-vn",
+		"-af", fmt.Sprintf("loudnorm=I=%g:TP=%g:LRA=%g:print_format=json", target.Integrated, target.TruePeak, target.Range),
+		"-f", "null", "-")
+	return cmd
+}
+
+/* Function to normalize the loudness of an audio using measured values (second loudnorm pass)
+ *
+ * Parameters:
+ *		inputPath - directory of the audio
+ *		target - the loudness target
+ *		measured - the values measured by the first pass
+ *		outputPath - directory to save the normalized WAV audio
+ * Returns:
+ *		exectauble ffmpeg cmd
+ */
+func CmdNormalizeLoudness(inputPath string, target LoudnessTarget, measured LoudnessMeasurement, outputPath string) *exec.Cmd {
+	cmd := exec.Command("ffmpeg", "-hide_banner", "-i", inputPath,
+		"-af", fmt.Sprintf("loudnorm=I=%g:TP=%g:LRA=%g:measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:offset=%s:linear=true:print_format=json",
+			target.Integrated, target.TruePeak, target.Range,
+			measured.InputI, measured.InputTP, measured.InputLRA, measured.InputThresh, measured.TargetOffset),
+		"-ar", "48000", "-codec:a", "pcm_s16le",
+		"-y", outputPath)
+	return cmd
+}
+
+/* Function to normalize the loudness of the mixed audio to an EBU R128 target with two loudnorm passes
+ *
+ * Parameters:
+ *		mixPath - path to the mixed WAV audio (see MixAudio), replaced by the normalized audio
+ *		target - the loudness target
+ *		v - verbose flag to determine what feedback to print
+ * Returns:
+ *		the loudness measured before normalization and the values reported by the normalization pass
+ */
+func NormalizeLoudness(mixPath string, target LoudnessTarget, v bool) LoudnessMeasurement {
+	fmt.Printf("Normalizing loudness to %g LUFS...\n", target.Integrated)
+
+	cmd := CmdMeasureLoudness(mixPath, target)
+	output, err := cmd.CombinedOutput()
+	CheckCMDError(output, err)
+	measured, err := parseLoudnorm(string(output))
+	helper.Check(err)
+	if v {
+		fmt.Printf("Measured loudness: %s LUFS, true peak %s dBTP, range %s LU\n", measured.InputI, measured.InputTP, measured.InputLRA)
+	}
+
+	normalizedMix := strings.TrimSuffix(mixPath, ".wav") + "_normalized.wav"
+	cmd = CmdNormalizeLoudness(mixPath, target, measured, normalizedMix)
+	output, err = cmd.CombinedOutput()
+	CheckCMDError(output, err)
+	normalized, err := parseLoudnorm(string(output))
+	helper.Check(err)
+
+	err = os.Rename(normalizedMix, mixPath)
+	helper.Check(err)
+
+	// Keep the first pass input values and the second pass output values
+	measured.OutputI = normalized.OutputI
+	measured.OutputTP = normalized.OutputTP
+	measured.OutputLRA = normalized.OutputLRA
+	measured.OutputThresh = normalized.OutputThresh
+	measured.TargetOffset = normalized.TargetOffset
+	fmt.Printf("Loudness normalized from %s to %s LUFS (true peak %s dBTP)\n", measured.InputI, measured.OutputI, measured.OutputTP)
+
+	return measured
+}
+
+/* Structure of the sidechain compression that ducks the background music under the narration
+ *	Threshold: narration level in dB above which the music is lowered
+ *	Ratio: amount the music is lowered by, from 1 to 20
+ *	Attack: time in milliseconds to lower the music once the narration starts
+ *	Release: time in milliseconds to bring the music back once the narration stops
+ */
+type Ducking struct {
+	Threshold float64
+	Ratio     float64
+	Attack    float64
+	Release   float64
+}
+
+/* Function to get the sidechaincompress filter of the ducking
+ *
+ * Returns:
+ *		the filter options, the threshold is converted from dB to the linear level ffmpeg expects
+ */
+func (d Ducking) filter() string {
+	threshold := math.Pow(10, d.Threshold/20)
+	return fmt.Sprintf("sidechaincompress=threshold=%.4f:ratio=%g:attack=%g:release=%g", threshold, d.Ratio, d.Attack, d.Release)
+}
+
+/* Function to mix background music under a narration audio
+ *
+ * Parameters:
+ *		backgroundMusicDir - directory to the background music
+ *		narrationAudioDir - directory to the narration audio
+ *		startTime - time in milliseconds the background music is played from
+ *		narrationStartTime - time in milliseconds the narration is played from
+ *		duration - max duration in milliseconds of both audios
+ *		ducking - sidechain compression lowering the music under the narration, nil to mix at equal weight
+ *		musicFade - fade-in and fade-out of the background music
+ *		outputPath - directory to save the mixed WAV audio
+ * Returns:
+ *		exectauble ffmpeg cmd
+ */
+func CmdMergeAudios(backgroundMusicDir string, narrationAudioDir string, startTime string, narrationStartTime string, duration string, ducking *Ducking, musicFade Fade, outputPath string) *exec.Cmd {
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(duration), 64)
+	helper.Check(err)
+	fades := musicFade.filter(seconds / 1000)
+
+	if fades != "" {
+		// afade is timed from the start of the trimmed music
+		fades = ",asetpts=PTS-STARTPTS" + fades
+	}
+
+	filter := fmt.Sprintf("[0:a]atrim=start=%sms:duration=%sms%s[a1];[1:a]atrim=start=%sms:duration=%sms,asetpts=expr=PTS-STARTPTS[a2];[a1][a2]amix=inputs=2,%s[a]", startTime, duration, fades, narrationStartTime, duration, intermediateFormat)
+	if ducking != nil {
+		// The narration is split so one copy drives the compressor on the music and the other is mixed in
+		filter = fmt.Sprintf("[0:a]atrim=start=%sms:duration=%sms,asetpts=PTS-STARTPTS%s[music];[1:a]atrim=start=%sms:duration=%sms,asetpts=PTS-STARTPTS,asplit=2[voice][sidechain];"+
+			"[music][sidechain]%s[ducked];[ducked][voice]amix=inputs=2,%s[a]", startTime, duration, musicFade.filter(seconds/1000), narrationStartTime, duration, ducking.filter(), intermediateFormat)
+	}
+
+	cmd := exec.Command("ffmpeg", "-i", backgroundMusicDir, "-i", narrationAudioDir, "-filter_complex", filter,
+		"-map", "[a]", "-codec:a", "pcm_s16le", "-y", outputPath)
+	return cmd
+}
+
+/* Structure of the fades of the audio
+ *	MusicIn: length in seconds of the fade-in where the background music starts
+ *	MusicOut: length in seconds of the fade-out where the background music stops
+ *	Click: length in seconds of the short fades at each cut that prevent clicks
+ *	Crossfade: whether to crossfade the audio of consecutive slides over their transition instead of cutting
+ */
+type AudioFades struct {
+	MusicIn   float64
+	MusicOut  float64
+	Click     float64
+	Crossfade bool
+}
+
+/* Structure of the fades of a slide's audio
+ *	In: length in seconds of the fade-in at the start
+ *	Out: length in seconds of the fade-out at the end
+ */
+type Fade struct {
+	In  float64
+	Out float64
+}
+
+/* Function to get the afade filters of a fade
+ *
+ * Parameters:
+ *		duration - length in seconds of the audio being faded
+ * Returns:
+ *		the filters to append to a filter chain, empty if there is no fade
+ */
+func (f Fade) filter(duration float64) string {
+	filter := ""
+	if f.In > 0 {
+		filter += fmt.Sprintf(",afade=t=in:st=0:d=%f", math.Min(f.In, duration))
+	}
+	if f.Out > 0 {
+		out := math.Min(f.Out, duration)
+		filter += fmt.Sprintf(",afade=t=out:st=%f:d=%f", duration-out, out)
+	}
+	return filter
+}
+
+/* Structure of a pause found by the silencedetect filter
+ *	Start: time in seconds the silence starts
+ *	End: time in seconds the silence ends
+ */
+type Silence struct {
+	Start float64
+	End   float64
+}
+
+/* Function to parse the silences printed by the silencedetect filter
+ *
+ * Parameters:
+ *		output - the combined ffmpeg output
+ * Returns:
+ *		the silences in order, a silence still running at the end of the audio is left out
+ */
+func parseSilences(output string) []Silence {
+	silences := []Silence{}
+	start := -1.0
+	for _, line := range strings.Split(output, "\n") {
+		if i := strings.Index(line, "silence_start: "); i >= 0 {
+			fields := strings.Fields(line[i+len("silence_start: "):])
+			if len(fields) > 0 {
+				if value, err := strconv.ParseFloat(fields[0], 64); err == nil {
+					start = math.Max(value, 0)
+				}
+			}
+		}
+		if i := strings.Index(line, "silence_end: "); i >= 0 && start >= 0 {
+			fields := strings.Fields(line[i+len("silence_end: "):])
+			if len(fields) > 0 {
+				if value, err := strconv.ParseFloat(fields[0], 64); err == nil {
+					silences = append(silences, Silence{start, value})
+				}
+			}
+			start = -1
+		}
+	}
+	return silences
+}
+
+/* Function to detect the pauses of an audio
+ *
+ * Parameters:
+ *		inputPath - directory of the audio or video file
+ *		noise - level in dB below which the audio counts as silence
+ *		minDuration - shortest silence in seconds to detect
+ * Returns:
+ *		exectauble ffmpeg cmd
+ */
+func CmdDetectSilence(inputPath string, noise float64, minDuration float64) *exec.Cmd {
+	cmd := exec.Command("ffmpeg", "-hide_banner", "-i", inputPath, "-vn",
+		"-af", fmt.Sprintf("silencedetect=noise=%gdB:d=%g", noise, minDuration),
+		"-f", "null", "-")
+	return cmd
+}
+
+/* Function to find the pauses of a narration
+ *
+ * Parameters:
+ *		inputPath - directory of the audio
+ *		noise - level in dB below which the audio counts as silence
+ *		minDuration - shortest silence in seconds to detect
+ * Returns:
+ *		the silences in order
+ */
+func DetectSilences(inputPath string, noise float64, minDuration float64) []Silence {
+	cmd := CmdDetectSilence(inputPath, noise, minDuration)
+	output, err := cmd.CombinedOutput()
+	CheckCMDError(output, err)
+	return parseSilences(string(output))
+}
+
+/* Function to cut a segment out of an audio, e.g. the narration of a story out of a chapter audio
+ *
+ * Parameters:
+ *		inputPath - directory of the audio
+ *		start - time in seconds the segment starts
+ *		duration - length in seconds of the segment
+ *		outputPath - directory to save the WAV segment
+ * Returns:
+ *		exectauble ffmpeg cmd
+ */
+func CmdExtractAudio(inputPath string, start float64, duration float64, outputPath string) *exec.Cmd {
+	// Seeking after the input decodes up to the start, so the cut is sample accurate
+	cmd := exec.Command("ffmpeg", "-i", inputPath, "-ss", fmt.Sprintf("%f", start), "-t", fmt.Sprintf("%f", duration),
+		"-vn", "-ar", "48000", "-ac", "2", "-codec:a", "pcm_s16le", "-y", outputPath)
+	return cmd
+}
+
+/* Function to cut a segment out of an audio
+ *
+ * Parameters:
+ *		inputPath - directory of the audio
+ *		start - time in seconds the segment starts
+ *		end - time in seconds the segment ends
+ *		outputPath - directory to save the WAV segment
+ */
+func ExtractAudio(inputPath string, start float64, end float64, outputPath string) {
+	cmd := CmdExtractAudio(inputPath, start, end-start, outputPath)
+	output, err := cmd.CombinedOutput()
+	CheckCMDError(output, err)
+}
@@ -0,0 +1,77 @@
+package ffmpeg_pkg
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// Aspect ratio of the output frame
+const frameAspect = 16.0 / 9.0
+
+// Policies fitting a motion rectangle to the aspect ratio of the output frame
+var fitPolicies = []string{"fit", "fill"}
+
+/* Function to check a fit policy name
+ *
+ * Parameters:
+ *		policy - the name, e.g. from the fit attribute of <motion>
+ * Returns:
+ *		an error if the policy is unknown
+ */
+func CheckFit(policy string) error {
+	for _, p := range fitPolicies {
+		if policy == p {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown fit %q, expected fit or fill", policy)
+}
+
+/* Function to fit a motion rectangle to the aspect ratio of the output frame
+ *
+ * Parameters:
+ *		rect - the rectangle as left, top, width and height fractions of the image
+ *		width - width of the image in pixels
+ *		height - height of the image in pixels
+ *		policy - "fit" shows all of the rectangle, adding image around it, "fill" fills the frame with the rectangle, cutting some of it off
+ * Returns:
+ *		the left, top, width and height of the crop box in pixels, centred on the rectangle and within the image
+ */
+func cropBox(rect []float64, width float64, height float64, policy string) (float64, float64, float64, float64) {
+	w, h := rect[2]*width, rect[3]*height
+	centreX, centreY := (rect[0]+rect[2]/2)*width, (rect[1]+rect[3]/2)*height
+
+	// A rectangle wider than the frame grows taller to fit, or is cut narrower to fill
+	if (w/h > frameAspect) == (policy == "fill") {
+		w = h * frameAspect
+	} else {
+		h = w / frameAspect
+	}
+	// The box cannot be larger than the image
+	if w > width {
+		w, h = width, width/frameAspect
+	}
+	if h > height {
+		w, h = height*frameAspect, height
+	}
+
+	left := math.Min(math.Max(centreX-w/2, 0), width-w)
+	top := math.Min(math.Max(centreY-h/2, 0), height-h)
+	return left, top, w, h
+}
+
+/* Function to get the pixels of the image shown by a motion rectangle in the output frame
+ *
+ * Parameters:
+ *		rect - the rectangle as left, top, width and height fractions of the image
+ *		width - width of the image in pixels
+ *		height - height of the image in pixels
+ *		policy - "fit" or "fill" (see cropBox)
+ * Returns:
+ *		the crop box in pixels
+ */
+func CropBox(rect []float64, width int, height int, policy string) image.Rectangle {
+	left, top, w, h := cropBox(rect, float64(width), float64(height), policy)
+	return image.Rect(int(math.Round(left)), int(math.Round(top)), int(math.Round(left+w)), int(math.Round(top+h)))
+}
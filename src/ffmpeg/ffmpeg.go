@@ -3,6 +3,7 @@ package ffmpeg_pkg
 import (
 	"fmt"
 	"log"
+	"os"
 	"os/exec"
 	"path"
 	"regexp"
@@ -66,10 +67,13 @@ func compareVersion(version string) string {
  *		Timings - Array of timing duration for the audio for each image
  *		Audios - Array of filenames for the audios to be used
  *		Motions - Array of start and end rectangles to use for the zoom/pan effects
+ *		Easings - Array of easing curves of the zoom/pan effects
+ *		Fits - Array of policies fitting the rectangles to the output frame
+ *		renderer - "ffmpeg" to use the zoompan filter, "go" to compute the frames in Go (see RenderKenBurns)
  *		tempPath - Filepath to the temporary directory to store each temp video
  *		v - verbose flag to determine what feedback to print
  */
-func MakeTempVideosWithoutAudio(Images []string, Timings []string, Audios []string, Motions [][][]float64, tempPath string, v bool) {
+func MakeTempVideosWithoutAudio(Images []string, Timings []string, Audios []string, Motions [][][]float64, Easings []string, Fits []string, renderer string, tempPath string, v bool) {
 	fmt.Println("Making temporary videos in parallel...")
 	totalNumImages := len(Images)
 
@@ -89,7 +93,7 @@ func MakeTempVideosWithoutAudio(Images []string, Timings []string, Audios []stri
 			// At the end of the goroutine, tell the WaitGroup
 			//   that another thread has completed.
 			defer wg.Done()
-			zoom_cmd := CreateZoomCommand(Motions[i], helper.ConvertStringToFloat(duration)[0])
+			zoom_cmd := CreateZoomCommand(Motions[i], helper.ConvertStringToFloat(duration)[0], Easings[i], Fits[i])
 			if v {
 				fmt.Println(fmt.Sprintf("Making temp%d-%d.mp4 video with:\n	Image: %s\n	Duration: %s ms\n	Start Rectangle (left, top, width, height): %f\n	End Rectangle (left, top, width, height): %f\n	Zoom Cmd: %s\n",
 					i+1, totalNumImages, Images[i], duration, Motions[i][0], Motions[i][1], zoom_cmd))
@@ -97,7 +101,13 @@ func MakeTempVideosWithoutAudio(Images []string, Timings []string, Audios []stri
 				fmt.Println(fmt.Sprintf("Making temp%d-%d.mp4 video", i+1, totalNumImages))
 			}
 
-			cmd := CmdCreateTempVideo(Images[i], duration, zoom_cmd, fmt.Sprintf(path.Join(tempPath, "temp%d-%d.mp4"), i, totalNumImages))
+			tempVideo := fmt.Sprintf(path.Join(tempPath, "temp%d-%d.mp4"), i, totalNumImages)
+			if renderer == "go" {
+				err := RenderKenBurns(Images[i], Motions[i], duration, Easings[i], Fits[i], tempVideo)
+				helper.Check(err)
+				return
+			}
+			cmd := CmdCreateTempVideo(Images[i], duration, zoom_cmd, tempVideo)
 			output, err := cmd.CombinedOutput()
 			CheckCMDError(output, err)
 		}(i)
@@ -115,11 +125,14 @@ func MakeTempVideosWithoutAudio(Images []string, Timings []string, Audios []stri
  *		Images - Array of filenames for the images
  *		Transitions - Array of Xfade transition names to use
  *		TransitionDurations - Array of durations for each transition
+ *		TransitionSplits - Array of splits of each transition around the start of the next slide (see TransitionLead)
  *		Timings - array of timing duration for the audio for each image
  *		tempPath - path to the temp folder where the videos are stored
  *		v - verbose flag to determine what feedback to print
+ * Returns:
+ *		the start time in seconds of each slide in the merged video, followed by the total length (see SlideOffsets)
  */
-func MergeTempVideos(Images []string, Transitions []string, TransitionDurations []string, Timings []string, tempPath string, v bool) {
+func MergeTempVideos(Images []string, Transitions []string, TransitionDurations []string, TransitionSplits []string, Timings []string, tempPath string, v bool) []float64 {
 	fmt.Println("Merging temporary videos...")
 	video_fade_filter := ""
 	settb := ""
@@ -127,48 +140,40 @@ func MergeTempVideos(Images []string, Transitions []string, TransitionDurations
 
 	totalNumImages := len(Images)
 
-	video_total_length := 0.0
 	video_each_length := make([]float64, totalNumImages)
 
 	input_files := []string{}
 
-	prev_offset := make([]float64, totalNumImages)
-	prev_offset[0] = 0.0
-
 	for i := 0; i < totalNumImages; i++ {
 
 		input_files = append(input_files, "-i", fmt.Sprintf(path.Join(tempPath, "temp%d-%d.mp4"), i, totalNumImages))
 
+		//get the current video length in seconds
+		video_each_length[i] = GetVideoLength(fmt.Sprintf(path.Join(tempPath, "temp%d-%d.mp4"), i, totalNumImages))
+	}
+
+	//get the total video length of the videos combined before each slide in seconds
+	prev_offset := SlideOffsets(video_each_length)
+	durations := transitionSeconds(TransitionDurations)
+	transition_start := TransitionOffsets(video_each_length, durations, TransitionSplits)
+
+	for i := 0; i < totalNumImages; i++ {
+		//add the time sacrificed to xfade before and after the clip, so it still starts and ends at its slide's offsets
+		settb += fmt.Sprintf("[%d:v]tpad=start_mode=clone:start_duration=%f:stop_mode=clone:stop_duration=%f[v%d];",
+			i, transitionBefore(durations, TransitionSplits, i), transitionAfter(durations, TransitionSplits, i), i)
 	}
 
 	for i := 0; i < totalNumImages-1; i++ {
 		transition := Transitions[i]
 
-		transition_duration, err := strconv.ParseFloat(strings.TrimSpace(string(TransitionDurations[i])), 8)
-		helper.Check(err)
-		transition_duration = transition_duration / 1000
-
 		if v {
-			fmt.Printf("%dth merge has transition %s and duration %f\n", i, transition, transition_duration)
+			fmt.Printf("%dth merge has transition %s, duration %f and split %s\n", i, transition, durations[i], TransitionSplits[i])
 		}
-		//add time to the video that is sacrificied to xfade
-		settb += fmt.Sprintf("[%d:v]tpad=stop_mode=clone:stop_duration=%f[v%d];", i, transition_duration, i)
-
-		//get the current video length in seconds
-		video_each_length[i] = GetVideoLength(fmt.Sprintf(path.Join(tempPath, "temp%d-%d.mp4"), i, totalNumImages))
-
-		//get the total video length of the videos combined thus far in seconds
-		video_total_length += video_each_length[i]
 
 		next_fade_output := fmt.Sprintf("v%d%d", i, i+1)
 
-		if i < totalNumImages-2 {
-			video_fade_filter += fmt.Sprintf("[%s][v%d]xfade=transition=%s:duration=%f:offset=%f", last_fade_output, i+1,
-				transition, transition_duration, video_total_length)
-		} else {
-			video_fade_filter += fmt.Sprintf("[%s][%d:v]xfade=transition=%s:duration=%f:offset=%f", last_fade_output, i+1,
-				transition, transition_duration, video_total_length)
-		}
+		video_fade_filter += fmt.Sprintf("[%s][v%d]xfade=transition=%s:duration=%f:offset=%f", last_fade_output, i+1,
+			transition, durations[i], transition_start[i])
 
 		last_fade_output = next_fade_output
 
@@ -179,6 +184,9 @@ func MergeTempVideos(Images []string, Transitions []string, TransitionDurations
 		}
 
 	}
+	if totalNumImages == 1 {
+		video_fade_filter = "[v0]format=yuv420p"
+	}
 
 	input_files = append(input_files, "-filter_complex", settb+video_fade_filter, "-y", path.Join(tempPath, "video_with_no_audio.mp4"))
 
@@ -186,6 +194,125 @@ func MergeTempVideos(Images []string, Transitions []string, TransitionDurations
 
 	output, err := cmd.CombinedOutput()
 	CheckCMDError(output, err)
+
+	return prev_offset
+}
+
+/* Function to find where each slide starts in the merged video. Each slide's clip keeps its length and
+ * its transitions are placed around the start of the slides (see TransitionOffsets), so slide i starts
+ * after the lengths of all the previous clips.
+ *
+ * Parameters:
+ *		lengths - length in seconds of each slide's clip
+ * Returns:
+ *		the start time in seconds of each slide, followed by the total length of the merged video
+ */
+func SlideOffsets(lengths []float64) []float64 {
+	offsets := make([]float64, len(lengths)+1)
+	for i, length := range lengths {
+		offsets[i+1] = offsets[i] + length
+	}
+	return offsets
+}
+
+// Part of a transition played before the start of the next slide, by split
+var transitionSplits = map[string]float64{
+	"centre": 0.5, // half at the end of the outgoing slide, half at the start of the incoming one
+	"end":    1,   // at the end of the outgoing slide
+	"start":  0,   // at the start of the incoming slide
+}
+
+/* Function to check a transition split name
+ *
+ * Parameters:
+ *		split - the name, e.g. from the split attribute of <transition>
+ * Returns:
+ *		an error if the split is unknown
+ */
+func CheckSplit(split string) error {
+	if _, found := transitionSplits[split]; !found {
+		return fmt.Errorf("unknown transition split %q, expected centre, end or start", split)
+	}
+	return nil
+}
+
+/* Function to get the length of the part of a transition played before the start of the next slide
+ *
+ * Parameters:
+ *		split - "centre", "end" or "start"
+ *		duration - length in seconds of the transition
+ * Returns:
+ *		the length in seconds of the transition over the end of the outgoing slide, the rest is over the start of the incoming one
+ */
+func TransitionLead(split string, duration float64) float64 {
+	return transitionSplits[split] * duration
+}
+
+/* Function to find where each transition starts in the merged video
+ *
+ * Parameters:
+ *		lengths - length in seconds of each slide's clip
+ *		durations - length in seconds of the transition from each slide into the next
+ *		splits - split of the transition from each slide into the next
+ * Returns:
+ *		the start time in seconds of the transition from each slide but the last into the next
+ */
+func TransitionOffsets(lengths []float64, durations []float64, splits []string) []float64 {
+	offsets := SlideOffsets(lengths)
+	starts := make([]float64, len(lengths)-1)
+	for i := range starts {
+		starts[i] = offsets[i+1] - TransitionLead(splits[i], durations[i])
+	}
+	return starts
+}
+
+/* Private function to parse the transition durations
+ *
+ * Parameters:
+ *		TransitionDurations - Array of durations in milliseconds for each transition
+ * Returns:
+ *		the durations in seconds
+ */
+func transitionSeconds(TransitionDurations []string) []float64 {
+	durations := make([]float64, len(TransitionDurations))
+	for i, duration := range TransitionDurations {
+		milliseconds, err := strconv.ParseFloat(strings.TrimSpace(duration), 64)
+		helper.Check(err)
+		durations[i] = milliseconds / 1000
+	}
+	return durations
+}
+
+/* Private function to get the length of the transition into a slide played over the end of the previous one
+ *
+ * Parameters:
+ *		durations - length in seconds of the transition from each slide into the next
+ *		splits - split of the transition from each slide into the next
+ *		i - index of the slide
+ * Returns:
+ *		the length in seconds the slide's clip is shown before its start, 0 for the first slide
+ */
+func transitionBefore(durations []float64, splits []string, i int) float64 {
+	if i == 0 {
+		return 0
+	}
+	return TransitionLead(splits[i-1], durations[i-1])
+}
+
+/* Private function to get the length of the transition from a slide played over the start of the next one
+ *
+ * Parameters:
+ *		durations - length in seconds of the transition from each slide into the next
+ *		splits - split of the transition from each slide into the next
+ *		i - index of the slide
+ * Returns:
+ *		the length in seconds the slide's clip is shown after its end, 0 for the last slide
+ */
+func transitionAfter(durations []float64, splits []string, i int) float64 {
+	if i == len(durations)-1 {
+		return 0
+	}
+	return durations[i] - TransitionLead(splits[i], durations[i])
 }
 
 /** Merges the temporary videos using the old fade method with just plain crossfade transitions
@@ -193,68 +320,58 @@ func MergeTempVideos(Images []string, Transitions []string, TransitionDurations
  *	Parameters:
  *		Images - Array of filenames for the images
  *		TransitionDurations - Array of durations for each transition
+ *		TransitionSplits - Array of splits of each transition around the start of the next slide (see TransitionLead)
  *		Timings - array of timing duration for the audio for each image
  *		tempLocation - path to the temp folder where the videos are stored
  *		v - verbose flag to determine what feedback to print
+ * Returns:
+ *		the start time in seconds of each slide in the merged video, followed by the total length
  */
-func MergeTempVideosOldFade(Images []string, TransitionDurations []string, Timings []string, tempLocation string, v bool) {
+func MergeTempVideosOldFade(Images []string, TransitionDurations []string, TransitionSplits []string, Timings []string, tempLocation string, v bool) []float64 {
 	fmt.Println("Merging temporary videos with traditional fade...")
 	video_fade_filter := ""
 	last_fade_output := ""
 	settb := ""
 
 	totalNumImages := len(Images)
-	video_total_duration := 0.0
-	video_total_length_minus_fade_transition := 0.0
 
 	video_each_length := make([]float64, totalNumImages)
 
 	input_files := []string{}
 
-	prev_offset := make([]float64, totalNumImages)
-	prev_offset[0] = 0.0
-
 	for i := 0; i < totalNumImages; i++ {
 		input_files = append(input_files, "-i", fmt.Sprintf(path.Join(tempLocation, "temp%d-%d.mp4"), i, totalNumImages))
-	}
-
-	for i := 0; i < totalNumImages; i++ {
-		transition_duration, err := strconv.ParseFloat(strings.TrimSpace(string(TransitionDurations[i])), 8)
-		helper.Check(err)
-		transition_duration = transition_duration / 1000
-
-		if v {
-			fmt.Printf("%dth merge has default fade transition and duration %f\n", i, transition_duration)
-		}
 
 		//get the current video length in seconds
 		video_each_length[i] = GetVideoLength(fmt.Sprintf(path.Join(tempLocation, "temp%d-%d.mp4"), i, totalNumImages))
+	}
 
-		//get the total video length of the videos combined thus far in seconds
-		video_total_duration += video_each_length[i]
-
-		video_total_length_minus_fade_transition = video_total_duration - transition_duration
+	prev_offset := SlideOffsets(video_each_length)
+	durations := transitionSeconds(TransitionDurations)
+	transition_start := TransitionOffsets(video_each_length, durations, TransitionSplits)
 
-		//add time to the video that is sacrificied to xfade
-		settb += fmt.Sprintf("[%d:v]tpad=stop_mode=clone:stop_duration=%f[v%d];", i, transition_duration/2, i)
+	for i := 0; i < totalNumImages; i++ {
+		//add the time sacrificed to the fade before and after the clip, so it still starts and ends at its slide's offsets
+		settb += fmt.Sprintf("[%d:v]tpad=start_mode=clone:start_duration=%f:stop_mode=clone:stop_duration=%f[v%d];",
+			i, transitionBefore(durations, TransitionSplits, i), transitionAfter(durations, TransitionSplits, i), i)
 
 		if i == 0 {
 			video_fade_filter += "[v0]setpts=PTS-STARTPTS[v_0];"
-			last_fade_output += "[base][v_0]overlay[tmp1];"
-		} else if i != totalNumImages-1 {
-			video_fade_filter += fmt.Sprintf("[v%d]fade=in:st=0:d=%f:alpha=1,setpts=PTS-STARTPTS+((%f)/TB)[v_%d];",
-				i, transition_duration, video_total_duration-video_each_length[i], i)
-
-			last_fade_output += fmt.Sprintf("[tmp%d][v_%d]overlay[tmp%d];", i, i, i+1)
+			last_fade_output += "[base][v_0]"
 		} else {
+			if v {
+				fmt.Printf("%dth merge has default fade transition, duration %f and split %s\n", i-1, durations[i-1], TransitionSplits[i-1])
+			}
+			//the clip fades in over the previous one from the start of the transition into it
 			video_fade_filter += fmt.Sprintf("[v%d]fade=in:st=0:d=%f:alpha=1,setpts=PTS-STARTPTS+((%f)/TB)[v_%d];",
-				i, transition_duration, video_total_duration-video_each_length[i], i)
+				i, durations[i-1], transition_start[i-1], i)
 
-			last_fade_output += fmt.Sprintf("[tmp%d][v_%d]overlay,format=yuv420p[fv]", i, i)
+			last_fade_output += fmt.Sprintf("overlay[tmp%d];[tmp%d][v_%d]", i, i, i)
 		}
 	}
+	last_fade_output += "overlay,format=yuv420p[fv]"
 
-	setDimensions := fmt.Sprintf("color=black:%dx%d:d=%f[base];", 1280, 720, video_total_length_minus_fade_transition)
+	setDimensions := fmt.Sprintf("color=black:%dx%d:d=%f[base];", 1280, 720, prev_offset[totalNumImages])
 
 	input_files = append(input_files, "-filter_complex", setDimensions+settb+video_fade_filter+last_fade_output, "-map", "[fv]", "-y", path.Join(tempLocation, "video_with_no_audio.mp4"))
 
@@ -262,75 +379,177 @@ func MergeTempVideosOldFade(Images []string, TransitionDurations []string, Timin
 
 	output, err := cmd.CombinedOutput()
 	CheckCMDError(output, err)
+
+	return prev_offset
 }
 
-/* Function to add the background and narration audio onto the video_with_no_audio.mp4
+/* Structure of a clip of audio placed on the output timeline
+ *	Source: filepath to the audio, empty for silence
+ *	SourceStart: time in seconds the clip starts within the source
+ *	Start: time in seconds the clip starts in the output
+ *	Duration: length in seconds of the clip
+ *	Fade: fade-in and fade-out of the clip
+ */
+type AudioClip struct {
+	Source      string
+	SourceStart float64
+	Start       float64
+	Duration    float64
+	Fade        Fade
+}
+
+/* Function to build the filter graph that places audio clips one after another. Clips overlapping
+ * the previous clip are crossfaded into it, others are joined to it.
  * Parameters:
- *		Timings - array of timing duration for the audio for each image
- *		Audios - Array of filenames for the audios to be used
- *		tempPath - path to the temp folder where the audioless video is stored
+ *		clips - the clips in output order
+ *		firstInput - index of the first audio input, the inputs before it are used for other streams
  *		v - verbose flag to determine what feedback to print
+ * Returns:
+ *		the ffmpeg input arguments for the audios and the filter_complex graph producing the [a] stream
  */
-func AddAudio(Timings []string, Audios []string, tempPath string, v bool) {
-	fmt.Println("Adding audio...")
+func audioFilter(clips []AudioClip, firstInput int, v bool) ([]string, string) {
 	audio_inputs := []string{}
 
 	audio_filter := ""
 	audio_last_filter := ""
 
-	audio_inputs = append(audio_inputs, "-y", "-i", path.Join(tempPath, "video_with_no_audio.mp4"))
+	for n, clip := range clips {
+		if clip.Source == "" {
+			audio_inputs = append(audio_inputs, "-f", "lavfi", "-i", "anullsrc=r=48000:cl=stereo")
+		} else {
+			audio_inputs = append(audio_inputs, "-i", clip.Source)
+		}
 
-	for i := 0; i < len(Audios); i++ {
-		if Audios[i] != "" {
-			audio_inputs = append(audio_inputs, "-i", Audios[i])
-			totalDuration := 0.0
+		// Sources of any format are converted to the same layout so they can be joined
+		audio_filter += fmt.Sprintf("[%d:a]apad,atrim=start=%f:duration=%f,asetpts=expr=PTS-STARTPTS,%s%s[a%d];",
+			firstInput+n, clip.SourceStart, clip.Duration, intermediateFormat, clip.Fade.filter(clip.Duration), n+1)
 
-			for j := 0; j < i; j++ {
-				if Audios[i] == Audios[j] || Audios[j] == path.Join(tempPath, "mergedAudio.mp3") {
-					transition_duration, err := strconv.ParseFloat(strings.TrimSpace(Timings[j]), 8)
-					helper.Check(err)
-					transition_duration = transition_duration / 1000
-					totalDuration += transition_duration
-				}
-			}
+		if v {
+			fmt.Printf("Adding audio snippet from %s at %.2f seconds to video. Source start = %.2f seconds\n", clip.Source, clip.Start, clip.SourceStart)
+		}
+	}
 
-			//place the audio at the start of each slide
-			audio_filter += fmt.Sprintf("[%d:a]atrim=start=%f:duration=%sms,asetpts=expr=PTS-STARTPTS[a%d];", i+1, totalDuration, strings.TrimSpace(Timings[i]), i+1)
-			audio_last_filter += fmt.Sprintf("[a%d]", i+1)
+	last_output := "[a1]"
+	for n := 1; n < len(clips); n++ {
+		next_output := fmt.Sprintf("[ax%d]", n)
+		if n == len(clips)-1 {
+			next_output = "[a]"
+		}
 
-			if v {
-				fmt.Printf("Adding audio snippet from %s to video. Total duration = %.2f seconds\n", Audios[i], totalDuration)
-			}
+		overlap := clips[n-1].Start + clips[n-1].Duration - clips[n].Start
+		if overlap > 0.0005 {
+			audio_last_filter += fmt.Sprintf("%s[a%d]acrossfade=d=%f%s", last_output, n+1, overlap, next_output)
+		} else {
+			audio_last_filter += fmt.Sprintf("%s[a%d]concat=n=2:v=0:a=1%s", last_output, n+1, next_output)
+		}
+		if n < len(clips)-1 {
+			audio_last_filter += ";"
 		}
+		last_output = next_output
+	}
+	if len(clips) == 1 {
+		audio_last_filter = "[a1]anull[a]"
 	}
-
-	audio_last_filter += fmt.Sprintf("concat=n=%d:v=0:a=1[a]", len(Audios)-1)
 	audio_filter += audio_last_filter
 
-	audio_inputs = append(audio_inputs, "-filter_complex", audio_filter, "-map", "0:v", "-map", "[a]", "-codec:v", "copy", "-codec:a", "libmp3lame", path.Join(tempPath, "merged_video.mp4"))
+	return audio_inputs, audio_filter
+}
+
+// Sample format, rate and channels of the lossless intermediate audio
+const intermediateFormat = "aformat=sample_fmts=s16:sample_rates=48000:channel_layouts=stereo"
+
+/* Function to mix the background and narration audio of all the slides to a lossless intermediate
+ * Parameters:
+ *		clips - the audio clips in output order
+ *		tempPath - path to the temp folder
+ *		v - verbose flag to determine what feedback to print
+ * Returns:
+ *		path to the mixed WAV audio
+ */
+func MixAudio(clips []AudioClip, tempPath string, v bool) string {
+	fmt.Println("Mixing audio...")
+	mixPath := path.Join(tempPath, "mix.wav")
+
+	inputs, audio_filter := audioFilter(clips, 0, v)
+	args := append([]string{"-y"}, inputs...)
+	args = append(args, "-filter_complex", audio_filter, "-map", "[a]", "-codec:a", "pcm_s16le", mixPath)
+
+	cmd := exec.Command("ffmpeg", args...)
+	output, err := cmd.CombinedOutput()
+	CheckCMDError(output, err)
+
+	return mixPath
+}
+
+/* Function to add the mixed background and narration audio onto the video_with_no_audio.mp4, producing final.mp4
+ * Parameters:
+ *		mixPath - path to the mixed audio (see MixAudio)
+ *		tempPath - path to the temp folder where the audioless video is stored
+ *		v - verbose flag to determine what feedback to print
+ */
+func AddAudio(mixPath string, tempPath string, v bool) {
+	fmt.Println("Adding audio...")
 
 	if v {
 		println("Adding compiled audio to merged video and generating final result...")
 	}
-	cmd := exec.Command("ffmpeg", audio_inputs...)
+	cmd := exec.Command("ffmpeg", "-y", "-i", path.Join(tempPath, "video_with_no_audio.mp4"), "-i", mixPath,
+		"-map", "0:v", "-map", "1:a", "-codec:v", "copy", "-codec:a", "libmp3lame", "-q:a", "2", path.Join(tempPath, "final.mp4"))
 	output, err := cmd.CombinedOutput()
 	CheckCMDError(output, err)
+}
+
+// Audio codecs used for each audio-only output format
+var audioCodecs = map[string][]string{
+	"mp3":  {"-codec:a", "libmp3lame", "-q:a", "2"},
+	"m4a":  {"-codec:a", "aac", "-b:a", "160k"},
+	"opus": {"-codec:a", "libopus", "-b:a", "96k"},
+}
+
+/* Function to check whether an audio-only output format is supported
+ *
+ * Parameters:
+ *		format - the format (file extension) to check
+ * Returns:
+ *		true if ExportAudio can write the format
+ */
+func IsAudioFormat(format string) bool {
+	_, ok := audioCodecs[format]
+	return ok
+}
 
-	trimEnd(tempPath)
+/* Function to encode the mixed background and narration audio, the same mix AddAudio adds to the video, to an audio file
+ * Parameters:
+ *		mixPath - path to the mixed audio (see MixAudio)
+ *		format - "mp3", "m4a" or "opus"
+ *		totalDuration - length of the audio in seconds
+ *		outputPath - path of the audio file to write
+ */
+func ExportAudio(mixPath string, format string, totalDuration float64, outputPath string) {
+	fmt.Printf("Exporting audio to %s...\n", outputPath)
+
+	args := []string{"-y", "-i", mixPath, "-t", fmt.Sprintf("%f", totalDuration)}
+	args = append(args, audioCodecs[format]...)
+	args = append(args, outputPath)
+
+	cmd := exec.Command("ffmpeg", args...)
+	output, err := cmd.CombinedOutput()
+	CheckCMDError(output, err)
 }
 
-/* Function to merge the background and narration audio to a temporary merged audio
+/* Function to merge the background and narration audio of a slide to a lossless temporary audio
  * Parameters:
- *		backgroundMusicDir - directory to the background music mp3
- *		narrationAudioDir - directory to the narration audio mp3
- *		startTime - define the start time in milliseconds of the background music mp3
+ *		backgroundMusicDir - directory to the background music
+ *		narrationAudioDir - directory to the narration audio
+ *		startTime - define the start time in milliseconds of the background music
+ *		narrationStartTime - define the start time in milliseconds of the narration audio
  *		duration - define the max duration in milliseconds of both audios
- *		tempPath - directory of the temporary path with all the temp items
+ *		ducking - sidechain compression lowering the music under the narration, nil to mix at equal weight
+ *		musicFade - fade-in and fade-out of the background music
+ *		outputPath - path of the WAV audio to write, unique to the slide
  */
-func MergeAudios(backgroundMusicDir string, narrationAudioDir string, startTime string, duration string, tempPath string) {
-	cmd := exec.Command("ffmpeg", "-i", backgroundMusicDir, "-i", narrationAudioDir, "-filter_complex",
-		fmt.Sprintf("[0:a]atrim=start=%sms:duration=%sms[a1];[1:a]atrim=start=0:duration=%sms,asetpts=expr=PTS+0[a2];[a1][a2]amix=inputs=2[a]", startTime, duration, duration),
-		"-map", "[a]", "-c:v", "copy", "-y", path.Join(tempPath, "mergedAudio.mp3"))
+func MergeAudios(backgroundMusicDir string, narrationAudioDir string, startTime string, narrationStartTime string, duration string, ducking *Ducking, musicFade Fade, outputPath string) {
+	cmd := CmdMergeAudios(backgroundMusicDir, narrationAudioDir, startTime, narrationStartTime, duration, ducking, musicFade, outputPath)
 	output, err := cmd.CombinedOutput()
 	CheckCMDError(output, err)
 }
@@ -341,24 +560,137 @@ func MergeAudios(backgroundMusicDir string, narrationAudioDir string, startTime
  * Parameters:
  *		tempPath - path to the temp folder
  *		outputFolder - path to the folder to store the final result
- *		name - name to label the final video
+ *		name - filename (may include sub folders) to label the final video, relative to the output folder
+ *		metadata - ffmpeg metadata arguments to tag the final video with, no tags are written if empty
+ *		chaptersFile - FFMETADATA file with the chapters to write to the final video, no chapters are written if empty
  */
-func CopyFinal(tempPath string, outputFolder string, name string) {
+func CopyFinal(tempPath string, outputFolder string, name string, metadata []string, chaptersFile string) {
 	// If -o is specified, save the final video at the specified location
 	// Else save it to the folder of the executable
 	var outputName string
 	if len(outputFolder) > 0 {
-		outputName = path.Join(outputFolder, name+".mp4")
+		outputName = path.Join(outputFolder, name)
 	} else { // If -o is not specified, save the final video at the default location
-		outputName = name + ".mp4"
+		outputName = name
 	}
 
+	err := os.MkdirAll(path.Dir(outputName), os.ModePerm)
+	helper.Check(err)
+
 	fmt.Printf("Copying final video from temp folder to %s...\n", outputName)
 	cmd := CmdCopyFile(path.Join(tempPath, "final.mp4"), outputName)
+	if len(metadata) > 0 || chaptersFile != "" {
+		cmd = CmdCopyFileWithMetadata(path.Join(tempPath, "final.mp4"), chaptersFile, outputName, metadata)
+	}
 	output, err := cmd.CombinedOutput()
 	CheckCMDError(output, err)
 }
 
+/* Function to create a short preview from the temporary videos made by MakeTempVideosWithoutAudio,
+ * as an MP4 and as animated GIF and WebP images for sharing
+ *
+ * Parameters:
+ *		totalNumVideos - number of temporary videos to join
+ *		seconds - maximum length of the preview, 0 for no limit
+ *		tempPath - path to the temp folder where the videos are stored
+ *		outputBase - path and filename without extension to save the previews to
+ */
+func MakePreview(totalNumVideos int, seconds float64, tempPath string, outputBase string) {
+	videos := []string{}
+	for i := 0; i < totalNumVideos; i++ {
+		videos = append(videos, fmt.Sprintf(path.Join(tempPath, "temp%d-%d.mp4"), i, totalNumVideos))
+	}
+
+	fmt.Printf("Creating %s-preview.mp4...\n", outputBase)
+	cmd := CmdConcatPreview(videos, seconds, 640, outputBase+"-preview.mp4")
+	output, err := cmd.CombinedOutput()
+	CheckCMDError(output, err)
+
+	fmt.Printf("Creating %s-preview.gif...\n", outputBase)
+	cmd = CmdCreateGif(outputBase+"-preview.mp4", 480, outputBase+"-preview.gif")
+	output, err = cmd.CombinedOutput()
+	CheckCMDError(output, err)
+
+	fmt.Printf("Creating %s-preview.webp...\n", outputBase)
+	cmd = CmdCreateWebp(outputBase+"-preview.mp4", 480, outputBase+"-preview.webp")
+	output, err = cmd.CombinedOutput()
+	CheckCMDError(output, err)
+}
+
+/* Function to create adaptive streaming output (HLS and/or DASH) from the final video
+ *
+ * Parameters:
+ *		finalVideo - path to the final video
+ *		subtitles - path to a WebVTT file to add as an HLS subtitle rendition, ignored if empty
+ *		subtitlesLang - language of the subtitles (ISO 639-2), ignored if empty
+ *		heights - pixel height of each rendition
+ *		hls - whether to create an HLS ladder in outputBase-hls
+ *		dash - whether to create a DASH manifest in outputBase-dash
+ *		outputBase - path and filename without extension to save the streaming folders to
+ */
+func CreateStreams(finalVideo string, subtitles string, subtitlesLang string, heights []int, hls bool, dash bool, outputBase string) {
+	if hls {
+		fmt.Printf("Creating HLS renditions %v in %s-hls...\n", heights, outputBase)
+		err := os.MkdirAll(outputBase+"-hls", os.ModePerm)
+		helper.Check(err)
+		cmd := CmdCreateHLS(finalVideo, subtitles, subtitlesLang, heights, outputBase+"-hls")
+		output, err := cmd.CombinedOutput()
+		CheckCMDError(output, err)
+	}
+
+	if dash {
+		fmt.Printf("Creating DASH renditions %v in %s-dash...\n", heights, outputBase)
+		err := os.MkdirAll(outputBase+"-dash", os.ModePerm)
+		helper.Check(err)
+		cmd := CmdCreateDASH(finalVideo, heights, outputBase+"-dash")
+		output, err := cmd.CombinedOutput()
+		CheckCMDError(output, err)
+
+		// The DASH muxer cannot segment WebVTT, so the subtitles are provided as a sidecar file
+		if subtitles != "" {
+			data, err := os.ReadFile(subtitles)
+			helper.Check(err)
+			err = os.WriteFile(path.Join(outputBase+"-dash", "subtitles.vtt"), data, 0644)
+			helper.Check(err)
+		}
+	}
+}
+
+/* Structure of a chapter marker
+ *	Start: start time of the chapter in seconds
+ *	End: end time of the chapter in seconds
+ *	Title: label shown by players for the chapter
+ */
+type Chapter struct {
+	Start float64
+	End   float64
+	Title string
+}
+
+/* Function to write chapter markers to an FFMETADATA file that can be muxed into the final video
+ *
+ * Parameters:
+ *		chapters - the chapters to write
+ *		tempPath - path to the temp folder to write the file to
+ * Returns:
+ *		the path to the written file
+ */
+func WriteChapters(chapters []Chapter, tempPath string) string {
+	escaper := strings.NewReplacer("\\", "\\\\", "=", "\\=", ";", "\\;", "#", "\\#", "\n", "\\\n")
+
+	metadata := ";FFMETADATA1\n"
+	for _, chapter := range chapters {
+		metadata += fmt.Sprintf("\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=%d\nEND=%d\ntitle=%s\n",
+			int64(chapter.Start*1000), int64(chapter.End*1000), escaper.Replace(chapter.Title))
+	}
+
+	chaptersFile := path.Join(tempPath, "chapters.txt")
+	err := os.WriteFile(chaptersFile, []byte(metadata), 0644)
+	helper.Check(err)
+
+	return chaptersFile
+}
+
 /* Function that creates an overlaid video between created video and testing video to see the differences between the two.
  *	One video is made half-transparent, changed to its negative image, and overlaid on the other video so that all similarities would cancel out and leave only the differences.
  * Parameters:
@@ -6,6 +6,7 @@ import (
 	"math"
 	"os/exec"
 	"path"
+	"strings"
 )
 
 /* Function to get the ffmpeg version
@@ -37,25 +38,6 @@ func CmdScaleImage(imagePath string, height string, width string, imageOutputPat
 	return cmd
 }
 
-/* Function to trim the video to a specified duration
- *
- * Parameters:
- *		duration - the length of the video in seconds
- *		tempPath - temporary directory path where all the temp files are saved
- * Returns:
-		exectauble command
-*/
-func CmdTrimLengthOfVideo(duration string, tempPath string) *exec.Cmd {
-	cmd := exec.Command("ffmpeg",
-		"-i", path.Join(tempPath, "merged_video.mp4"),
-		"-c", "copy", "-t", duration,
-		"-y",
-		path.Join(tempPath, "final.mp4"),
-	)
-
-	return cmd
-}
-
 /* Function to get the length (seconds) of a video
  *
  * Parameters:
@@ -96,35 +78,74 @@ func CmdCreateTempVideo(ImageDirectory string, duration string, zoom_cmd string,
 	return cmd
 }
 
+// Easing curves of the zoom/pan effects, as ffmpeg expressions of the progress %[1]s going from 0 to 1
+var easings = map[string]string{
+	"linear":      "%[1]s",
+	"ease-in-out": "%[1]s*%[1]s*(3-2*%[1]s)",
+	"cubic":       "if(lt(%[1]s,0.5),4*%[1]s*%[1]s*%[1]s,1-pow(2-2*%[1]s,3)/2)",
+	"sine":        "(1-cos(PI*%[1]s))/2",
+}
+
+/* Function to check an easing curve name
+ *
+ * Parameters:
+ *		easing - the name, e.g. from the easing attribute of <motion>
+ * Returns:
+ *		an error if the easing is unknown
+ */
+func CheckEasing(easing string) error {
+	if _, found := easings[easing]; !found {
+		return fmt.Errorf("unknown easing %q, expected linear, ease-in-out, cubic or sine", easing)
+	}
+	return nil
+}
+
 /* Function to generate a proper ffmpeg filter to apply the zoom/pan effects
  *
  * Parameters:
  *		Motions - array of motion data containing start and end rectangles
  *		TimingDuration - duration of the zoom/pan effect
+ *		easing - easing curve of the motion: linear, ease-in-out, cubic or sine
+ *		fit - how rectangles of another aspect ratio than the output are fitted to it: fit or fill (see cropBox)
  * Returns:
  *		final_cmd - the finalized zoom/pan command for a single video
  */
-func CreateZoomCommand(Motions [][]float64, TimingDuration float64) string {
+func CreateZoomCommand(Motions [][]float64, TimingDuration float64, easing string, fit string) string {
 	num_frames := int(TimingDuration / (1000.0 / 25.0))
+	if num_frames < 1 {
+		num_frames = 1
+	}
 
-	size_init := Motions[0][3]
-	size_change := Motions[1][3] - size_init
-	size_incr := size_change / float64(num_frames)
+	curve, found := easings[easing]
+	if !found {
+		curve = easings["linear"]
+	}
+	// The last frame shows exactly the end rectangle, like RenderKenBurns
+	last_frame := num_frames - 1
+	if last_frame < 1 {
+		last_frame = 1
+	}
+	progress := fmt.Sprintf("clip((on-1)/%d,0,1)", last_frame)
+	eased := fmt.Sprintf(curve, progress)
 
-	var x_init float64 = Motions[0][0]
-	var x_end float64 = Motions[1][0]
-	var x_change float64 = x_end - x_init
-	var x_incr float64 = x_change / float64(num_frames)
+	// The images are 16:9 (see CropImage), so the boxes are computed on a 16:9 image and zoompan,
+	// which zooms both dimensions alike, only needs their width
+	start_x, start_y, start_w, _ := cropBox(Motions[0], 16, 9, fit)
+	end_x, end_y, end_w, _ := cropBox(Motions[1], 16, 9, fit)
 
-	var y_init float64 = Motions[0][1]
-	var y_end float64 = Motions[1][1]
-	var y_change float64 = y_end - y_init
-	var y_incr float64 = y_change / float64(num_frames)
+	size_init := start_w / 16
+	size_change := end_w/16 - size_init
+	x_init := start_x / 16
+	x_change := end_x/16 - x_init
+	y_init := start_y / 9
+	y_change := end_y/9 - y_init
 
-	zoom_cmd := fmt.Sprintf("1/((%.10f)%s(%.10f)*on)", size_init-size_incr, checkSign(size_incr), math.Abs(size_incr))
-	x_cmd := fmt.Sprintf("%0.10f*iw%s%0.10f*iw*on", x_init-x_incr, checkSign(x_incr), math.Abs(x_incr))
-	y_cmd := fmt.Sprintf("%0.10f*ih%s%0.10f*ih*on", y_init-y_incr, checkSign(y_incr), math.Abs(y_incr))
-	final_cmd := fmt.Sprintf("scale=8000:-1,zoompan=z='%s':x='%s':y='%s':d=%d:fps=25,scale=1280:720,setsar=1:1", zoom_cmd, x_cmd, y_cmd, num_frames)
+	zoom_cmd := fmt.Sprintf("1/(%.10f%s%.10f*%s)", size_init, checkSign(size_change), math.Abs(size_change), eased)
+	x_cmd := fmt.Sprintf("(%.10f%s%.10f*%s)*iw", x_init, checkSign(x_change), math.Abs(x_change), eased)
+	y_cmd := fmt.Sprintf("(%.10f%s%.10f*%s)*ih", y_init, checkSign(y_change), math.Abs(y_change), eased)
+	// The image is upscaled and the frames are rendered at twice the output size then scaled down, so the
+	// crop moves by fractions of an output pixel instead of jumping a pixel at a time
+	final_cmd := fmt.Sprintf("scale=8000:-1,zoompan=z='%s':x='%s':y='%s':d=%d:fps=25:s=2560x1440,scale=1280:720,setsar=1:1", zoom_cmd, x_cmd, y_cmd, num_frames)
 
 	return final_cmd
 }
@@ -154,6 +175,201 @@ func CmdCopyFile(to string, from string) *exec.Cmd {
 	return cmd
 }
 
+/* Function to copy a video from one location to another without re-encoding and tag it with metadata and chapters
+ *
+ * Parameters:
+ *		from - directory of the video
+ *		chapters - directory of an FFMETADATA file with chapters, ignored if empty
+ *		to - directory to save the tagged video
+ *		metadata - ffmpeg metadata arguments (e.g. "-metadata", "title=The Word")
+ * Returns:
+ *		exectauble ffmpeg cmd
+ */
+func CmdCopyFileWithMetadata(from string, chapters string, to string, metadata []string) *exec.Cmd {
+	args := []string{"-i", from}
+	if chapters != "" {
+		args = append(args, "-i", chapters, "-map_chapters", "1")
+	}
+	args = append(args, "-map", "0", "-codec", "copy")
+	args = append(args, metadata...)
+	args = append(args, "-y", to)
+	cmd := exec.Command("ffmpeg", args...)
+	return cmd
+}
+
+/* Function to extract a single frame of a video as an image
+ *
+ * Parameters:
+ *		videoPath - directory of the video
+ *		seconds - time of the frame to extract
+ *		imageOutputPath - directory to save the image
+ * Returns:
+ *		exectauble ffmpeg cmd
+ */
+func CmdExtractFrame(videoPath string, seconds float64, imageOutputPath string) *exec.Cmd {
+	cmd := exec.Command("ffmpeg", "-ss", fmt.Sprintf("%.3f", seconds), "-i", videoPath,
+		"-frames:v", "1", "-q:v", "2", "-y", imageOutputPath)
+	return cmd
+}
+
+/* Function to join videos one after another into a short preview without audio
+ *
+ * Parameters:
+ *		videoPaths - directories of the videos to join in order
+ *		seconds - maximum length of the preview, 0 for no limit
+ *		width - pixel width of the preview
+ *		outputPath - directory to save the preview video
+ * Returns:
+ *		exectauble ffmpeg cmd
+ */
+func CmdConcatPreview(videoPaths []string, seconds float64, width int, outputPath string) *exec.Cmd {
+	args := []string{}
+	filter := ""
+	for i, videoPath := range videoPaths {
+		args = append(args, "-i", videoPath)
+		filter += fmt.Sprintf("[%d:v]", i)
+	}
+	filter += fmt.Sprintf("concat=n=%d:v=1:a=0,scale=%d:-2,format=yuv420p[v]", len(videoPaths), width)
+	args = append(args, "-filter_complex", filter, "-map", "[v]")
+	if seconds > 0 {
+		args = append(args, "-t", fmt.Sprintf("%.3f", seconds))
+	}
+	args = append(args, "-y", outputPath)
+	cmd := exec.Command("ffmpeg", args...)
+	return cmd
+}
+
+/* Function to convert a video to an animated GIF with an optimised palette
+ *
+ * Parameters:
+ *		videoPath - directory of the video
+ *		width - pixel width of the GIF
+ *		outputPath - directory to save the GIF
+ * Returns:
+ *		exectauble ffmpeg cmd
+ */
+func CmdCreateGif(videoPath string, width int, outputPath string) *exec.Cmd {
+	cmd := exec.Command("ffmpeg", "-i", videoPath,
+		"-filter_complex", fmt.Sprintf("fps=12,scale=%d:-1:flags=lanczos,split[a][b];[a]palettegen=stats_mode=diff[p];[b][p]paletteuse=dither=bayer:bayer_scale=5:diff_mode=rectangle", width),
+		"-loop", "0", "-y", outputPath)
+	return cmd
+}
+
+/* Function to convert a video to an animated WebP
+ *
+ * Parameters:
+ *		videoPath - directory of the video
+ *		width - pixel width of the WebP
+ *		outputPath - directory to save the WebP
+ * Returns:
+ *		exectauble ffmpeg cmd
+ */
+func CmdCreateWebp(videoPath string, width int, outputPath string) *exec.Cmd {
+	cmd := exec.Command("ffmpeg", "-i", videoPath,
+		"-vf", fmt.Sprintf("fps=12,scale=%d:-1:flags=lanczos", width),
+		"-c:v", "libwebp", "-quality", "70", "-loop", "0", "-an", "-y", outputPath)
+	return cmd
+}
+
+// Video bitrates used for each rendition height of the adaptive streaming ladders
+var renditionBitrates = map[int]int{240: 400, 360: 800, 480: 1400, 720: 2800, 1080: 5000}
+
+/* Function to create the ffmpeg arguments that scale a video into several renditions
+ *
+ * Parameters:
+ *		heights - pixel height of each rendition
+ * Returns:
+ *		the filter_complex, map and encoding arguments, with rendition i mapped to v:i and a:i
+ */
+func renditionArgs(heights []int) []string {
+	filter := fmt.Sprintf("[0:v]split=%d", len(heights))
+	for i := range heights {
+		filter += fmt.Sprintf("[v%d]", i)
+	}
+	for i, height := range heights {
+		filter += fmt.Sprintf(";[v%d]scale=-2:%d[v%dout]", i, height, i)
+	}
+
+	args := []string{"-filter_complex", filter}
+	for i := range heights {
+		args = append(args, "-map", fmt.Sprintf("[v%dout]", i), "-map", "0:a")
+	}
+	args = append(args, "-c:v", "libx264", "-preset", "veryfast", "-g", "50", "-keyint_min", "50", "-sc_threshold", "0")
+	for i, height := range heights {
+		bitrate, ok := renditionBitrates[height]
+		if !ok {
+			bitrate = height * 4
+		}
+		args = append(args, fmt.Sprintf("-b:v:%d", i), fmt.Sprintf("%dk", bitrate),
+			fmt.Sprintf("-maxrate:v:%d", i), fmt.Sprintf("%dk", bitrate*107/100),
+			fmt.Sprintf("-bufsize:v:%d", i), fmt.Sprintf("%dk", bitrate*3/2))
+	}
+	args = append(args, "-c:a", "aac", "-b:a", "128k", "-ac", "2")
+	return args
+}
+
+/* Function to create an HLS ladder (segments, variant playlists and a master playlist) from a video
+ *
+ * Parameters:
+ *		videoPath - directory of the video
+ *		subtitlesPath - directory of a WebVTT file to add as a subtitle rendition, ignored if empty
+ *		subtitlesLang - language of the subtitles (ISO 639-2), ignored if empty
+ *		heights - pixel height of each rendition
+ *		outputDirectory - directory to save the streaming files to
+ * Returns:
+ *		exectauble ffmpeg cmd
+ */
+func CmdCreateHLS(videoPath string, subtitlesPath string, subtitlesLang string, heights []int, outputDirectory string) *exec.Cmd {
+	args := []string{"-i", videoPath}
+	if subtitlesPath != "" {
+		args = append(args, "-i", subtitlesPath)
+	}
+	args = append(args, renditionArgs(heights)...)
+
+	streamMap := []string{}
+	for i, height := range heights {
+		variant := fmt.Sprintf("v:%d,a:%d,name:%dp", i, i, height)
+		if subtitlesPath != "" {
+			args = append(args, "-map", "1:s")
+			variant += fmt.Sprintf(",s:%d,sgroup:subs", i)
+		}
+		streamMap = append(streamMap, variant)
+	}
+	if subtitlesPath != "" {
+		args = append(args, "-c:s", "webvtt")
+		if subtitlesLang != "" {
+			args = append(args, "-metadata:s:s", "language="+subtitlesLang)
+		}
+	}
+
+	args = append(args, "-f", "hls", "-hls_time", "6", "-hls_playlist_type", "vod",
+		"-hls_segment_filename", path.Join(outputDirectory, "%v", "segment_%03d.ts"),
+		"-master_pl_name", "master.m3u8",
+		"-var_stream_map", strings.Join(streamMap, " "),
+		"-y", path.Join(outputDirectory, "%v", "index.m3u8"))
+	cmd := exec.Command("ffmpeg", args...)
+	return cmd
+}
+
+/* Function to create a DASH manifest with several renditions from a video
+ *
+ * Parameters:
+ *		videoPath - directory of the video
+ *		heights - pixel height of each rendition
+ *		outputDirectory - directory to save the streaming files to
+ * Returns:
+ *		exectauble ffmpeg cmd
+ */
+func CmdCreateDASH(videoPath string, heights []int, outputDirectory string) *exec.Cmd {
+	args := []string{"-i", videoPath}
+	args = append(args, renditionArgs(heights)...)
+	args = append(args, "-f", "dash", "-seg_duration", "6", "-use_template", "1", "-use_timeline", "1",
+		"-adaptation_sets", "id=0,streams=v id=1,streams=a",
+		"-y", path.Join(outputDirectory, "manifest.mpd"))
+	cmd := exec.Command("ffmpeg", args...)
+	return cmd
+}
+
 /* Function to check the sign of a number
  *
  * Parameters:
@@ -170,19 +386,3 @@ func checkSign(num float64) string {
 		return "+"
 	}
 }
-
-/* Function to trim the end of the video and remove excess empty audio when the audio file is longer than the video file
- *
- * Parameters:
- *		tempPath - directory of where all the temporary files are saved
- */
-func trimEnd(tempPath string) {
-	fmt.Println("Trimming end of merged video...")
-
-	video_length := GetVideoLength(tempPath + "/video_with_no_audio.mp4")
-
-	//match the video length of the merged video with the true length of the video
-	cmd := CmdTrimLengthOfVideo(fmt.Sprintf("%f", video_length), tempPath)
-	output, err := cmd.CombinedOutput()
-	CheckCMDError(output, err)
-}
@@ -1,11 +1,19 @@
 package ffmpeg_pkg
 
 import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"os"
 	"os/exec"
 	"path"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
+
+	"github.com/sillsdev/appbuilder-storybuilder/src/helper"
 )
 
 var ffmpeg string
@@ -72,35 +80,6 @@ func Test_CmdScaleImage(t *testing.T) {
 	}
 }
 
-func Test_CmdTrimLengthOfVideo(t *testing.T) {
-	type args struct {
-		duration string
-		tempPath string
-	}
-	tests := []struct {
-		name string
-		args args
-		want *exec.Cmd
-	}{
-		{
-			"trim length of video ffmpeg cmd",
-			args{duration: "19200ms", tempPath: "temp"},
-			exec.Command("ffmpeg",
-				"-i", path.Join("temp", "merged_video.mp4"),
-				"-c", "copy", "-t", "19200ms",
-				"-y",
-				path.Join("temp", "final.mp4")),
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := CmdTrimLengthOfVideo(tt.args.duration, tt.args.tempPath).String(); got != tt.want.String() {
-				t.Errorf("cmdTrimLengthOfVideo() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
 func Test_CmdGetVideoLength(t *testing.T) {
 	type args struct {
 		inputDirectory string
@@ -165,7 +144,10 @@ func Test_CreateZoomCommand(t *testing.T) {
 	type args struct {
 		Motions  [][]float64
 		Duration float64
+		Easing   string
+		Fit      string
 	}
+	motions := [][]float64{{0.282, 0.088, 0.718, 0.717}, {0.391, 0.115, 0.475, 0.478}}
 	tests := []struct {
 		name string
 		args args
@@ -173,18 +155,65 @@ func Test_CreateZoomCommand(t *testing.T) {
 	}{
 		{
 			"Creating zoom command for VB-John 1v1.jpg",
-			args{Motions: [][]float64{{0.282, 0.088, 0.718, 0.717}, {0.391, 0.115, 0.475, 0.478}},
-				Duration: 9400},
-			"scale=8000:-1,zoompan=z='1/((0.718)-(0.001)*on)':x='0.282*iw+0.000*iw*on':y='0.088*ih+0.000*ih*on':d=235:fps=25,scale=1280:720,setsar=1:1",
+			args{Motions: motions, Duration: 9400, Easing: "linear", Fit: "fit"},
+			"scale=8000:-1,zoompan=z='1/(0.7180000000-0.2400000000*clip((on-1)/234,0,1))':x='(0.2820000000+0.1075000000*clip((on-1)/234,0,1))*iw':y='(0.0875000000+0.0275000000*clip((on-1)/234,0,1))*ih':d=235:fps=25:s=2560x1440,scale=1280:720,setsar=1:1",
+		},
+		{
+			"Creating ease-in-out zoom command",
+			args{Motions: motions, Duration: 9400, Easing: "ease-in-out", Fit: "fit"},
+			"scale=8000:-1,zoompan=z='1/(0.7180000000-0.2400000000*clip((on-1)/234,0,1)*clip((on-1)/234,0,1)*(3-2*clip((on-1)/234,0,1)))':x='(0.2820000000+0.1075000000*clip((on-1)/234,0,1)*clip((on-1)/234,0,1)*(3-2*clip((on-1)/234,0,1)))*iw':y='(0.0875000000+0.0275000000*clip((on-1)/234,0,1)*clip((on-1)/234,0,1)*(3-2*clip((on-1)/234,0,1)))*ih':d=235:fps=25:s=2560x1440,scale=1280:720,setsar=1:1",
+		},
+		{
+			"Creating cubic zoom command",
+			args{Motions: motions, Duration: 9400, Easing: "cubic", Fit: "fit"},
+			"scale=8000:-1,zoompan=z='1/(0.7180000000-0.2400000000*if(lt(clip((on-1)/234,0,1),0.5),4*clip((on-1)/234,0,1)*clip((on-1)/234,0,1)*clip((on-1)/234,0,1),1-pow(2-2*clip((on-1)/234,0,1),3)/2))':x='(0.2820000000+0.1075000000*if(lt(clip((on-1)/234,0,1),0.5),4*clip((on-1)/234,0,1)*clip((on-1)/234,0,1)*clip((on-1)/234,0,1),1-pow(2-2*clip((on-1)/234,0,1),3)/2))*iw':y='(0.0875000000+0.0275000000*if(lt(clip((on-1)/234,0,1),0.5),4*clip((on-1)/234,0,1)*clip((on-1)/234,0,1)*clip((on-1)/234,0,1),1-pow(2-2*clip((on-1)/234,0,1),3)/2))*ih':d=235:fps=25:s=2560x1440,scale=1280:720,setsar=1:1",
+		},
+		{
+			"Creating sine zoom command",
+			args{Motions: motions, Duration: 9400, Easing: "sine", Fit: "fit"},
+			"scale=8000:-1,zoompan=z='1/(0.7180000000-0.2400000000*(1-cos(PI*clip((on-1)/234,0,1)))/2)':x='(0.2820000000+0.1075000000*(1-cos(PI*clip((on-1)/234,0,1)))/2)*iw':y='(0.0875000000+0.0275000000*(1-cos(PI*clip((on-1)/234,0,1)))/2)*ih':d=235:fps=25:s=2560x1440,scale=1280:720,setsar=1:1",
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := CreateZoomCommand(tt.args.Motions, tt.args.Duration); got != tt.want {
+			if got := CreateZoomCommand(tt.args.Motions, tt.args.Duration, tt.args.Easing, tt.args.Fit); got != tt.want {
 				t.Errorf("createZoomCommand() = %v, want %v", got, tt.want)
 			}
 		})
 	}
+
+	if err := CheckEasing("bounce"); err == nil {
+		t.Errorf("CheckEasing() with an unknown easing should fail")
+	}
+}
+
+func Test_CropBox(t *testing.T) {
+	tests := []struct {
+		name   string
+		rect   []float64
+		width  int
+		height int
+		fit    image.Rectangle
+		fill   image.Rectangle
+	}{
+		{"VB-John 1v1 start", []float64{0.282, 0.088, 0.718, 0.717}, 1280, 720, image.Rect(361, 63, 1280, 580), image.Rect(362, 63, 1279, 580)},
+		{"VB-John 1v1 end", []float64{0.391, 0.115, 0.475, 0.478}, 1280, 720, image.Rect(499, 83, 1110, 427), image.Rect(500, 84, 1108, 426)},
+		{"VB-John 1v3 start", []float64{0.297, 0.204, 0.554, 0.558}, 1280, 720, image.Rect(378, 147, 1092, 549), image.Rect(380, 148, 1089, 547)},
+		{"tall rectangle", []float64{0.4, 0.1, 0.2, 0.8}, 1280, 720, image.Rect(128, 72, 1152, 648), image.Rect(512, 288, 768, 432)},
+		{"wide rectangle", []float64{0, 0.3, 1, 0.3}, 1280, 720, image.Rect(0, 0, 1280, 720), image.Rect(448, 216, 832, 432)},
+		{"rectangle in a corner", []float64{0.7, 0.7, 0.3, 0.3}, 1280, 720, image.Rect(896, 504, 1280, 720), image.Rect(896, 504, 1280, 720)},
+		{"whole 4:3 image", []float64{0, 0, 1, 1}, 1024, 768, image.Rect(0, 96, 1024, 672), image.Rect(0, 96, 1024, 672)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CropBox(tt.rect, tt.width, tt.height, "fit"); got != tt.fit {
+				t.Errorf("CropBox() fit = %v, want %v", got, tt.fit)
+			}
+			if got := CropBox(tt.rect, tt.width, tt.height, "fill"); got != tt.fill {
+				t.Errorf("CropBox() fill = %v, want %v", got, tt.fill)
+			}
+		})
+	}
 }
 
 func Test_CmdCopyFile(t *testing.T) {
@@ -211,3 +240,478 @@ func Test_CmdCopyFile(t *testing.T) {
 		})
 	}
 }
+
+func Test_CmdCopyFileWithMetadata(t *testing.T) {
+	type args struct {
+		from     string
+		chapters string
+		to       string
+		metadata []string
+	}
+	tests := []struct {
+		name string
+		args args
+		want *exec.Cmd
+	}{
+		{
+			"copy file with metadata ffmpeg cmd",
+			args{from: "temp/final.mp4", to: "../en/The Word.mp4", metadata: []string{"-metadata", "title=The Word", "-metadata:s:a:0", "language=eng"}},
+			exec.Command("ffmpeg", "-i", "temp/final.mp4", "-map", "0", "-codec", "copy",
+				"-metadata", "title=The Word", "-metadata:s:a:0", "language=eng", "-y", "../en/The Word.mp4"),
+		},
+		{
+			"copy file with chapters ffmpeg cmd",
+			args{from: "temp/final.mp4", chapters: "temp/chapters.txt", to: "../final.mp4", metadata: []string{}},
+			exec.Command("ffmpeg", "-i", "temp/final.mp4", "-i", "temp/chapters.txt", "-map_chapters", "1",
+				"-map", "0", "-codec", "copy", "-y", "../final.mp4"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CmdCopyFileWithMetadata(tt.args.from, tt.args.chapters, tt.args.to, tt.args.metadata).String(); got != tt.want.String() {
+				t.Errorf("cmdCopyFileWithMetadata() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_SlideOffsets(t *testing.T) {
+	got := SlideOffsets([]float64{5, 9.4, 5.96})
+	want := []float64{0, 5, 14.4, 20.36}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Errorf("SlideOffsets()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func Test_TransitionOffsets(t *testing.T) {
+	lengths := []float64{5, 9.4, 5.96}
+	durations := []float64{1, 2, 3}
+	tests := []struct {
+		split string
+		want  []float64
+	}{
+		{"centre", []float64{4.5, 13.4}},
+		{"end", []float64{4, 12.4}},
+		{"start", []float64{5, 14.4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.split, func(t *testing.T) {
+			splits := []string{tt.split, tt.split, tt.split}
+			got := TransitionOffsets(lengths, durations, splits)
+			if len(got) != len(tt.want) {
+				t.Fatalf("TransitionOffsets() = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+					t.Errorf("TransitionOffsets()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+
+			// Each clip is padded by the transitions around it, so the merged video keeps the total length
+			total := 0.0
+			for i, length := range lengths {
+				total += transitionBefore(durations, splits, i) + length + transitionAfter(durations, splits, i)
+			}
+			for _, duration := range durations[:len(durations)-1] {
+				total -= duration
+			}
+			if math.Abs(total-20.36) > 1e-9 {
+				t.Errorf("merged length = %v, want 20.36", total)
+			}
+		})
+	}
+
+	// Transitions of different splits
+	got := TransitionOffsets(lengths, durations, []string{"end", "start", "centre"})
+	if math.Abs(got[0]-4) > 1e-9 || math.Abs(got[1]-14.4) > 1e-9 {
+		t.Errorf("TransitionOffsets() = %v, want [4 14.4]", got)
+	}
+	if err := CheckSplit("middle"); err == nil {
+		t.Errorf("CheckSplit() with an unknown split should fail")
+	}
+}
+
+func Test_CmdExtractFrame(t *testing.T) {
+	type args struct {
+		videoPath       string
+		seconds         float64
+		imageOutputPath string
+	}
+	tests := []struct {
+		name string
+		args args
+		want *exec.Cmd
+	}{
+		{
+			"extract poster frame ffmpeg cmd",
+			args{videoPath: "temp/final.mp4", seconds: 9.7, imageOutputPath: "../final-poster.jpg"},
+			exec.Command("ffmpeg", "-ss", "9.700", "-i", "temp/final.mp4", "-frames:v", "1", "-q:v", "2", "-y", "../final-poster.jpg"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CmdExtractFrame(tt.args.videoPath, tt.args.seconds, tt.args.imageOutputPath).String(); got != tt.want.String() {
+				t.Errorf("cmdExtractFrame() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_CmdConcatPreview(t *testing.T) {
+	type args struct {
+		videoPaths []string
+		seconds    float64
+		width      int
+		outputPath string
+	}
+	tests := []struct {
+		name string
+		args args
+		want *exec.Cmd
+	}{
+		{
+			"first seconds preview ffmpeg cmd",
+			args{videoPaths: []string{"temp/temp0-2.mp4", "temp/temp1-2.mp4"}, seconds: 10, width: 640, outputPath: "final-preview.mp4"},
+			exec.Command("ffmpeg", "-i", "temp/temp0-2.mp4", "-i", "temp/temp1-2.mp4",
+				"-filter_complex", "[0:v][1:v]concat=n=2:v=1:a=0,scale=640:-2,format=yuv420p[v]", "-map", "[v]",
+				"-t", "10.000", "-y", "final-preview.mp4"),
+		},
+		{
+			"selected slides preview ffmpeg cmd",
+			args{videoPaths: []string{"temp/temp0-1.mp4"}, seconds: 0, width: 640, outputPath: "final-preview.mp4"},
+			exec.Command("ffmpeg", "-i", "temp/temp0-1.mp4",
+				"-filter_complex", "[0:v]concat=n=1:v=1:a=0,scale=640:-2,format=yuv420p[v]", "-map", "[v]",
+				"-y", "final-preview.mp4"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CmdConcatPreview(tt.args.videoPaths, tt.args.seconds, tt.args.width, tt.args.outputPath).String(); got != tt.want.String() {
+				t.Errorf("cmdConcatPreview() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_CmdCreateHLS(t *testing.T) {
+	type args struct {
+		videoPath       string
+		subtitlesPath   string
+		subtitlesLang   string
+		heights         []int
+		outputDirectory string
+	}
+	tests := []struct {
+		name string
+		args args
+		want *exec.Cmd
+	}{
+		{
+			"hls ladder with subtitles ffmpeg cmd",
+			args{videoPath: "temp/final.mp4", subtitlesPath: "eng.vtt", subtitlesLang: "eng", heights: []int{360, 720}, outputDirectory: "out-hls"},
+			exec.Command("ffmpeg", "-i", "temp/final.mp4", "-i", "eng.vtt",
+				"-filter_complex", "[0:v]split=2[v0][v1];[v0]scale=-2:360[v0out];[v1]scale=-2:720[v1out]",
+				"-map", "[v0out]", "-map", "0:a", "-map", "[v1out]", "-map", "0:a",
+				"-c:v", "libx264", "-preset", "veryfast", "-g", "50", "-keyint_min", "50", "-sc_threshold", "0",
+				"-b:v:0", "800k", "-maxrate:v:0", "856k", "-bufsize:v:0", "1200k",
+				"-b:v:1", "2800k", "-maxrate:v:1", "2996k", "-bufsize:v:1", "4200k",
+				"-c:a", "aac", "-b:a", "128k", "-ac", "2",
+				"-map", "1:s", "-map", "1:s", "-c:s", "webvtt", "-metadata:s:s", "language=eng",
+				"-f", "hls", "-hls_time", "6", "-hls_playlist_type", "vod",
+				"-hls_segment_filename", "out-hls/%v/segment_%03d.ts",
+				"-master_pl_name", "master.m3u8",
+				"-var_stream_map", "v:0,a:0,name:360p,s:0,sgroup:subs v:1,a:1,name:720p,s:1,sgroup:subs",
+				"-y", "out-hls/%v/index.m3u8"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CmdCreateHLS(tt.args.videoPath, tt.args.subtitlesPath, tt.args.subtitlesLang, tt.args.heights, tt.args.outputDirectory).String(); got != tt.want.String() {
+				t.Errorf("cmdCreateHLS() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseLoudnorm(t *testing.T) {
+	output := `[Parsed_loudnorm_0 @ 0x5581] 
+{
+	"input_i" : "-23.54",
+	"input_tp" : "-4.21",
+	"input_lra" : "7.10",
+	"input_thresh" : "-33.96",
+	"output_i" : "-16.02",
+	"output_tp" : "-1.50",
+	"output_lra" : "6.40",
+	"output_thresh" : "-26.41",
+	"normalization_type" : "linear",
+	"target_offset" : "0.02"
+}`
+	want := LoudnessMeasurement{"-23.54", "-4.21", "7.10", "-33.96", "-16.02", "-1.50", "6.40", "-26.41", "0.02"}
+	got, err := parseLoudnorm(output)
+	if err != nil || got != want {
+		t.Errorf("parseLoudnorm() = %v, %v, want %v", got, err, want)
+	}
+
+	if _, err := parseLoudnorm("Output file is empty, nothing was encoded"); err == nil {
+		t.Errorf("parseLoudnorm() without measurement should fail")
+	}
+}
+
+func Test_CmdNormalizeLoudness(t *testing.T) {
+	target := LoudnessTarget{Integrated: -16, TruePeak: -1.5, Range: 11}
+	measured := LoudnessMeasurement{InputI: "-23.54", InputTP: "-4.21", InputLRA: "7.10", InputThresh: "-33.96", TargetOffset: "0.02"}
+	want := exec.Command("ffmpeg", "-hide_banner", "-i", "temp/mix.wav",
+		"-af", "loudnorm=I=-16:TP=-1.5:LRA=11:measured_I=-23.54:measured_TP=-4.21:measured_LRA=7.10:measured_thresh=-33.96:offset=0.02:linear=true:print_format=json",
+		"-ar", "48000", "-codec:a", "pcm_s16le",
+		"-y", "temp/mix_normalized.wav")
+	if got := CmdNormalizeLoudness("temp/mix.wav", target, measured, "temp/mix_normalized.wav").String(); got != want.String() {
+		t.Errorf("CmdNormalizeLoudness() = %v, want %v", got, want)
+	}
+}
+
+func Test_parseSilences(t *testing.T) {
+	output := `[silencedetect @ 0x55d1] silence_start: -0.0213
+[silencedetect @ 0x55d1] silence_end: 0.8125 | silence_duration: 0.8338
+size=N/A time=00:00:05.12 bitrate=N/A speed= 412x
+[silencedetect @ 0x55d1] silence_start: 9.1
+[silencedetect @ 0x55d1] silence_end: 9.7 | silence_duration: 0.6
+[silencedetect @ 0x55d1] silence_start: 21.45`
+	want := []Silence{{0, 0.8125}, {9.1, 9.7}}
+	if got := parseSilences(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSilences() = %v, want %v", got, want)
+	}
+}
+
+func Test_CmdEncodeFrames(t *testing.T) {
+	want := exec.Command("ffmpeg", "-f", "rawvideo", "-pix_fmt", "rgba", "-s", "1280x720", "-framerate", "25", "-i", "-",
+		"-t", "9400ms", "-vf", "setsar=1:1", "-pix_fmt", "yuv420p", "-y", "temp/temp1-8.mp4")
+	if got := CmdEncodeFrames("9400", "temp/temp1-8.mp4").String(); got != want.String() {
+		t.Errorf("CmdEncodeFrames() = %v, want %v", got, want)
+	}
+}
+
+func Test_frameRenderer(t *testing.T) {
+	// An image red on its left half and blue on its right half
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+	src := image.NewRGBA(image.Rect(0, 0, 64, 36))
+	draw.Draw(src, image.Rect(0, 0, 32, 36), &image.Uniform{red}, image.Point{}, draw.Src)
+	draw.Draw(src, image.Rect(32, 0, 64, 36), &image.Uniform{blue}, image.Point{}, draw.Src)
+
+	start := [4]float64{0, 0, 64, 36}
+	end := [4]float64{32, 0, 32, 18}
+	tests := []struct {
+		name string
+		box  [4]float64
+		x    int
+		want color.RGBA
+	}{
+		{"whole image, left", interpolateBox(start, end, 0), 4, red},
+		{"whole image, right", interpolateBox(start, end, 0), 28, blue},
+		{"halfway, left", interpolateBox(start, end, 0.5), 4, red},
+		{"halfway, right", interpolateBox(start, end, 0.5), 16, blue},
+		{"right quarter, left", interpolateBox(start, end, 1), 0, blue},
+		{"half a pixel across the edge", [4]float64{16.5, 0, 32, 18}, 15, color.RGBA{128, 0, 128, 255}},
+	}
+	renderer := newFrameRenderer(src)
+	dst := image.NewRGBA(image.Rect(0, 0, 32, 18))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			renderer.draw(dst, tt.box)
+			if got := dst.RGBAAt(tt.x, 9); got != tt.want {
+				t.Errorf("frameRenderer.draw() pixel %d = %v, want %v", tt.x, got, tt.want)
+			}
+		})
+	}
+
+	// The Go easing curves match the ffmpeg ones
+	for easing, curve := range easingCurves {
+		if _, found := easings[easing]; !found {
+			t.Errorf("easing %q has no ffmpeg expression", easing)
+		}
+		if curve(0) != 0 || math.Abs(curve(0.5)-0.5) > 1e-9 || curve(1) != 1 {
+			t.Errorf("easing %q does not go from 0 to 1 through 0.5", easing)
+		}
+	}
+}
+
+// Compares the time of drawing a frame of VB-John 1v1.jpg in Go
+func BenchmarkFrameRenderer(b *testing.B) {
+	img, err := helper.ReadImage("../../TestInput/VB-John 1v1.jpg")
+	if err != nil {
+		b.Fatal(err)
+	}
+	renderer := newFrameRenderer(img)
+	width, height := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+	var box [4]float64
+	box[0], box[1], box[2], box[3] = cropBox([]float64{0.282, 0.088, 0.718, 0.717}, width, height, "fit")
+	dst := image.NewRGBA(image.Rect(0, 0, frameWidth, frameHeight))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		renderer.draw(dst, box)
+	}
+}
+
+// Compares the time of rendering a 4 second slide with the zoompan filter and in Go, both need ffmpeg
+func BenchmarkRenderers(b *testing.B) {
+	image := "../../TestInput/VB-John 1v1.jpg"
+	motions := [][]float64{{0.282, 0.088, 0.718, 0.717}, {0.391, 0.115, 0.475, 0.478}}
+	output := path.Join(b.TempDir(), "temp.mp4")
+
+	b.Run("ffmpeg", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			cmd := CmdCreateTempVideo(image, "4000", CreateZoomCommand(motions, 4000, "linear", "fit"), output)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				b.Fatalf("%v: %s", err, out)
+			}
+		}
+	})
+	b.Run("go", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := RenderKenBurns(image, motions, "4000", "linear", "fit", output); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func Test_XfadeTransition(t *testing.T) {
+	registry := path.Join(t.TempDir(), "transitions.json")
+	err := os.WriteFile(registry, []byte(`{
+		"dip-to-white": {"xfade": "fadeblack"},
+		"iris": {"description": "opens a circle", "xfade": "circleopen"},
+		"dissolve-half": {"expr": "if(gt(P,0.5),A,B)"}
+	}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transitions, err := ReadTransitions(registry)
+	if err != nil {
+		t.Fatalf("ReadTransitions() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"wipeleft", "wipeleft", false},
+		{"dip-to-black", "fadeblack", false},
+		{"dip-to-white", "fadeblack", false},
+		{"iris", "circleopen", false},
+		{"soft-wipe", "custom:expr='A+(B-A)*clip((1.1-1.2*P-X/W)*10+0.5,0,1)'", false},
+		{"dissolve-half", "custom:expr='if(gt(P,0.5),A,B)'", false},
+		{"curl", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := XfadeTransition(transitions, tt.name)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("XfadeTransition() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	invalid := []Transition{
+		{Xfade: "curl"},
+		{Xfade: "fade", Expr: "A"},
+		{},
+		{Expr: "if(gt(P,0.5),A,B"},
+		{Expr: "A'"},
+		{Expr: "A;"},
+	}
+	for _, transition := range invalid {
+		if err := checkTransition(transition); err == nil {
+			t.Errorf("checkTransition(%+v) should fail", transition)
+		}
+	}
+}
+
+func Test_CmdDetectSilence(t *testing.T) {
+	want := exec.Command("ffmpeg", "-hide_banner", "-i", "narration.mp3", "-vn",
+		"-af", "silencedetect=noise=-35dB:d=0.3", "-f", "null", "-")
+	if got := CmdDetectSilence("narration.mp3", -35, 0.3).String(); got != want.String() {
+		t.Errorf("CmdDetectSilence() = %v, want %v", got, want)
+	}
+}
+
+func Test_CmdExtractAudio(t *testing.T) {
+	want := exec.Command("ffmpeg", "-i", "chapter.mp3", "-ss", "4.360000", "-t", "111.960000",
+		"-vn", "-ar", "48000", "-ac", "2", "-codec:a", "pcm_s16le", "-y", "temp/narration-01.wav")
+	if got := CmdExtractAudio("chapter.mp3", 4.36, 111.96, "temp/narration-01.wav").String(); got != want.String() {
+		t.Errorf("CmdExtractAudio() = %v, want %v", got, want)
+	}
+}
+
+func Test_CmdMergeAudios(t *testing.T) {
+	tests := []struct {
+		name    string
+		ducking *Ducking
+		fade    Fade
+		want    *exec.Cmd
+	}{
+		{
+			"equal weight mix ffmpeg cmd",
+			nil,
+			Fade{},
+			exec.Command("ffmpeg", "-i", "music.mp3", "-i", "narration.mp3", "-filter_complex",
+				"[0:a]atrim=start=5000ms:duration=9400ms[a1];[1:a]atrim=start=0ms:duration=9400ms,asetpts=expr=PTS-STARTPTS[a2];[a1][a2]amix=inputs=2,aformat=sample_fmts=s16:sample_rates=48000:channel_layouts=stereo[a]",
+				"-map", "[a]", "-codec:a", "pcm_s16le", "-y", "temp/mixed-02.wav"),
+		},
+		{
+			"ducked mix ffmpeg cmd",
+			&Ducking{Threshold: -30, Ratio: 8, Attack: 20, Release: 400},
+			Fade{},
+			exec.Command("ffmpeg", "-i", "music.mp3", "-i", "narration.mp3", "-filter_complex",
+				"[0:a]atrim=start=5000ms:duration=9400ms,asetpts=PTS-STARTPTS[music];[1:a]atrim=start=0ms:duration=9400ms,asetpts=PTS-STARTPTS,asplit=2[voice][sidechain];"+
+					"[music][sidechain]sidechaincompress=threshold=0.0316:ratio=8:attack=20:release=400[ducked];[ducked][voice]amix=inputs=2,aformat=sample_fmts=s16:sample_rates=48000:channel_layouts=stereo[a]",
+				"-map", "[a]", "-codec:a", "pcm_s16le", "-y", "temp/mixed-02.wav"),
+		},
+		{
+			"music fading out ffmpeg cmd",
+			nil,
+			Fade{Out: 2},
+			exec.Command("ffmpeg", "-i", "music.mp3", "-i", "narration.mp3", "-filter_complex",
+				"[0:a]atrim=start=5000ms:duration=9400ms,asetpts=PTS-STARTPTS,afade=t=out:st=7.400000:d=2.000000[a1];[1:a]atrim=start=0ms:duration=9400ms,asetpts=expr=PTS-STARTPTS[a2];[a1][a2]amix=inputs=2,aformat=sample_fmts=s16:sample_rates=48000:channel_layouts=stereo[a]",
+				"-map", "[a]", "-codec:a", "pcm_s16le", "-y", "temp/mixed-02.wav"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CmdMergeAudios("music.mp3", "narration.mp3", "5000", "0", "9400", tt.ducking, tt.fade, "temp/mixed-02.wav").String(); got != tt.want.String() {
+				t.Errorf("CmdMergeAudios() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_audioFilter(t *testing.T) {
+	clips := []AudioClip{
+		{Source: "music.mp3", SourceStart: 0, Start: 0, Duration: 6, Fade: Fade{In: 1, Out: 0.01}},
+		{Source: "narration.mp3", SourceStart: 0, Start: 5, Duration: 4, Fade: Fade{In: 0.01, Out: 0.01}},
+		{Source: "narration.mp3", SourceStart: 4, Start: 9, Duration: 3},
+		{Source: "", Start: 12, Duration: 5},
+	}
+
+	wantInputs := []string{"-i", "music.mp3", "-i", "narration.mp3", "-i", "narration.mp3", "-f", "lavfi", "-i", "anullsrc=r=48000:cl=stereo"}
+	wantFilter := "[1:a]apad,atrim=start=0.000000:duration=6.000000,asetpts=expr=PTS-STARTPTS,aformat=sample_fmts=s16:sample_rates=48000:channel_layouts=stereo,afade=t=in:st=0:d=1.000000,afade=t=out:st=5.990000:d=0.010000[a1];" +
+		"[2:a]apad,atrim=start=0.000000:duration=4.000000,asetpts=expr=PTS-STARTPTS,aformat=sample_fmts=s16:sample_rates=48000:channel_layouts=stereo,afade=t=in:st=0:d=0.010000,afade=t=out:st=3.990000:d=0.010000[a2];" +
+		"[3:a]apad,atrim=start=4.000000:duration=3.000000,asetpts=expr=PTS-STARTPTS,aformat=sample_fmts=s16:sample_rates=48000:channel_layouts=stereo[a3];" +
+		"[4:a]apad,atrim=start=0.000000:duration=5.000000,asetpts=expr=PTS-STARTPTS,aformat=sample_fmts=s16:sample_rates=48000:channel_layouts=stereo[a4];" +
+		"[a1][a2]acrossfade=d=1.000000[ax1];[ax1][a3]concat=n=2:v=0:a=1[ax2];[ax2][a4]concat=n=2:v=0:a=1[a]"
+
+	inputs, filter := audioFilter(clips, 1, false)
+	if !reflect.DeepEqual(inputs, wantInputs) || filter != wantFilter {
+		t.Errorf("audioFilter() = %v, %v, want %v, %v", inputs, filter, wantInputs, wantFilter)
+	}
+
+	// A narration shorter than its slide is padded with silence to the length of the slide
+	clips = []AudioClip{{Source: "narration.mp3", SourceStart: 2, Start: 0, Duration: 8}}
+	wantFilter = "[0:a]apad,atrim=start=2.000000:duration=8.000000,asetpts=expr=PTS-STARTPTS,aformat=sample_fmts=s16:sample_rates=48000:channel_layouts=stereo[a1];[a1]anull[a]"
+	if _, filter := audioFilter(clips, 0, false); filter != wantFilter {
+		t.Errorf("audioFilter() = %v, want %v", filter, wantFilter)
+	}
+}
@@ -0,0 +1,286 @@
+package ffmpeg_pkg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"math"
+	"os/exec"
+
+	"github.com/sillsdev/appbuilder-storybuilder/src/helper"
+	"golang.org/x/image/draw"
+)
+
+// Size and rate of the frames rendered in Go, the same as the output of CreateZoomCommand
+const (
+	frameWidth  = 1280
+	frameHeight = 720
+	frameRate   = 25
+)
+
+// Easing curves of the zoom/pan effects rendered in Go, the same as the ffmpeg expressions in easings
+var easingCurves = map[string]func(float64) float64{
+	"linear":      func(t float64) float64 { return t },
+	"ease-in-out": func(t float64) float64 { return t * t * (3 - 2*t) },
+	"cubic": func(t float64) float64 {
+		if t < 0.5 {
+			return 4 * t * t * t
+		}
+		return 1 - math.Pow(2-2*t, 3)/2
+	},
+	"sine": func(t float64) float64 { return (1 - math.Cos(math.Pi*t)) / 2 },
+}
+
+/* Function to encode raw RGBA frames read from stdin into a single audioless video
+ *
+ * Parameters:
+ *		duration - duration of the generated video (milliseconds)
+ *		finalOutputDirectory - directory to save the output video
+ * Returns:
+ *		exectauble ffmpeg cmd
+ */
+func CmdEncodeFrames(duration string, finalOutputDirectory string) *exec.Cmd {
+	cmd := exec.Command("ffmpeg", "-f", "rawvideo", "-pix_fmt", "rgba",
+		"-s", fmt.Sprintf("%dx%d", frameWidth, frameHeight), "-framerate", fmt.Sprint(frameRate), "-i", "-",
+		"-t", duration+"ms", "-vf", "setsar=1:1", "-pix_fmt", "yuv420p", "-y", finalOutputDirectory)
+	return cmd
+}
+
+// Resampler drawing crop boxes of an image, at any fraction of a pixel, onto the frames of a zoom/pan effect
+type frameRenderer struct {
+	src  *image.RGBA
+	rows []float32 // the rows of the crop box resampled to the frame width
+}
+
+// Source pixels and weights of one destination pixel along an axis
+type resampleTaps struct {
+	first   int
+	weights []float32
+}
+
+/* Function to create a frame renderer
+ *
+ * Parameters:
+ *		img - the image
+ * Returns:
+ *		the renderer, with its own RGBA copy of the image
+ */
+func newFrameRenderer(img image.Image) *frameRenderer {
+	src := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(src, src.Bounds(), img, img.Bounds().Min, draw.Src)
+	return &frameRenderer{src, nil}
+}
+
+/* Private function for the Catmull-Rom cubic kernel
+ *
+ * Parameters:
+ *		x - distance from the sample in pixels
+ * Returns:
+ *		the weight of the sample
+ */
+func catmullRom(x float64) float64 {
+	x = math.Abs(x)
+	if x < 1 {
+		return (1.5*x-2.5)*x*x + 1
+	}
+	if x < 2 {
+		return ((-0.5*x+2.5)*x-4)*x + 2
+	}
+	return 0
+}
+
+/* Private function to compute the source pixels and weights of each destination pixel along an axis
+ *
+ * Parameters:
+ *		offset - start of the crop box in source pixels
+ *		length - length of the crop box in source pixels
+ *		srcSize - number of source pixels, the ones past the edges repeat the edge pixel
+ *		dstSize - number of destination pixels
+ * Returns:
+ *		the taps of each destination pixel
+ */
+func computeTaps(offset float64, length float64, srcSize int, dstSize int) []resampleTaps {
+	scale := length / float64(dstSize)
+	// When shrinking, the kernel is widened so every source pixel contributes
+	stretch := math.Max(scale, 1)
+	taps := make([]resampleTaps, dstSize)
+	for i := range taps {
+		centre := offset + (float64(i)+0.5)*scale - 0.5
+		first := int(math.Ceil(centre - 2*stretch))
+		last := int(math.Floor(centre + 2*stretch))
+		weights := make([]float32, last-first+1)
+		sum := 0.0
+		for j := range weights {
+			weight := catmullRom((float64(first+j) - centre) / stretch)
+			weights[j] = float32(weight)
+			sum += weight
+		}
+		for j := range weights {
+			weights[j] /= float32(sum)
+		}
+		taps[i] = resampleTaps{first, weights}
+	}
+	return taps
+}
+
+/* Private function to clamp a pixel index to the image
+ *
+ * Parameters:
+ *		index - the index
+ *		size - the number of pixels
+ * Returns:
+ *		the index of the nearest pixel of the image
+ */
+func clampIndex(index int, size int) int {
+	if index < 0 {
+		return 0
+	}
+	if index >= size {
+		return size - 1
+	}
+	return index
+}
+
+/* Function to draw a crop box of the image onto a frame
+ *
+ * Parameters:
+ *		dst - the frame to draw
+ *		box - crop box in pixels (left, top, width, height)
+ */
+func (r *frameRenderer) draw(dst *image.RGBA, box [4]float64) {
+	width, height := dst.Bounds().Dx(), dst.Bounds().Dy()
+	srcWidth, srcHeight := r.src.Bounds().Dx(), r.src.Bounds().Dy()
+	columns := computeTaps(box[0], box[2], srcWidth, width)
+	lines := computeTaps(box[1], box[3], srcHeight, height)
+
+	// Horizontal pass over the source rows the vertical pass needs
+	top := lines[0].first
+	bottom := lines[height-1].first + len(lines[height-1].weights)
+	if size := (bottom - top) * width * 4; cap(r.rows) < size {
+		r.rows = make([]float32, size)
+	}
+	for y := top; y < bottom; y++ {
+		srcRow := r.src.Pix[clampIndex(y, srcHeight)*r.src.Stride:]
+		row := r.rows[(y-top)*width*4:]
+		for x, tap := range columns {
+			var red, green, blue, alpha float32
+			for j, weight := range tap.weights {
+				p := srcRow[clampIndex(tap.first+j, srcWidth)*4:]
+				red += weight * float32(p[0])
+				green += weight * float32(p[1])
+				blue += weight * float32(p[2])
+				alpha += weight * float32(p[3])
+			}
+			row[x*4], row[x*4+1], row[x*4+2], row[x*4+3] = red, green, blue, alpha
+		}
+	}
+
+	// Vertical pass into the frame, a whole row at a time
+	sum := make([]float32, width*4)
+	for y, tap := range lines {
+		for x := range sum {
+			sum[x] = 0
+		}
+		for j, weight := range tap.weights {
+			row := r.rows[(tap.first+j-top)*width*4:][:width*4]
+			for x, value := range row {
+				sum[x] += weight * value
+			}
+		}
+		dstRow := dst.Pix[y*dst.Stride:][:width*4]
+		for x, value := range sum {
+			switch {
+			case value <= 0:
+				dstRow[x] = 0
+			case value >= 255:
+				dstRow[x] = 255
+			default:
+				dstRow[x] = uint8(value + 0.5)
+			}
+		}
+	}
+}
+
+/* Private function to interpolate the crop box of a zoom/pan effect
+ *
+ * Parameters:
+ *		start - crop box in pixels (left, top, width, height) at the start of the effect
+ *		end - crop box in pixels at the end of the effect
+ *		progress - eased progress of the effect from 0 to 1
+ * Returns:
+ *		the crop box of the frame
+ */
+func interpolateBox(start [4]float64, end [4]float64, progress float64) [4]float64 {
+	var box [4]float64
+	for i := range box {
+		box[i] = start[i] + (end[i]-start[i])*progress
+	}
+	return box
+}
+
+/* Function to generate a single audioless video of an image with zoom/pan effects computed in Go
+ * instead of ffmpeg's zoompan filter, so the image is not upscaled
+ *
+ * Parameters:
+ *		imagePath - directory of the image location
+ *		Motions - start and end rectangles of the zoom/pan effects
+ *		duration - duration of the generated video (milliseconds)
+ *		easing - name of the easing curve of the zoom/pan effects
+ *		fit - policy fitting the rectangles to the output frame, "fit" or "fill"
+ *		finalOutputDirectory - directory to save the output video
+ * Returns:
+ *		err - error if the image cannot be read or the video cannot be encoded, with the output of ffmpeg
+ */
+func RenderKenBurns(imagePath string, Motions [][]float64, duration string, easing string, fit string, finalOutputDirectory string) error {
+	img, err := helper.ReadImage(imagePath)
+	if err != nil {
+		return err
+	}
+	renderer := newFrameRenderer(img)
+	curve, found := easingCurves[easing]
+	if !found {
+		curve = easingCurves["linear"]
+	}
+
+	width, height := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+	var start, end [4]float64
+	start[0], start[1], start[2], start[3] = cropBox(Motions[0], width, height, fit)
+	end[0], end[1], end[2], end[3] = cropBox(Motions[1], width, height, fit)
+
+	num_frames := int(helper.ConvertStringToFloat(duration)[0] / (1000.0 / frameRate))
+	if num_frames < 1 {
+		num_frames = 1
+	}
+
+	cmd := CmdEncodeFrames(duration, finalOutputDirectory)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	frame := image.NewRGBA(image.Rect(0, 0, frameWidth, frameHeight))
+	for i := 0; i < num_frames; i++ {
+		// The last frame shows exactly the end rectangle
+		progress := 0.0
+		if num_frames > 1 {
+			progress = float64(i) / float64(num_frames-1)
+		}
+		renderer.draw(frame, interpolateBox(start, end, curve(progress)))
+		// A failed write means ffmpeg stopped, its error is reported by Wait
+		if _, err := stdin.Write(frame.Pix); err != nil {
+			break
+		}
+	}
+	stdin.Close()
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("%v: %s", err, output.String())
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package ffmpeg_pkg
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// The transitions of the xfade filter
+var xfadeTransitions = []string{
+	"fade", "wipeleft", "wiperight", "wipeup", "wipedown", "slideleft", "slideright", "slideup", "slidedown",
+	"circlecrop", "rectcrop", "distance", "fadeblack", "fadewhite", "radial", "smoothleft", "smoothright",
+	"smoothup", "smoothdown", "circleopen", "circleclose", "vertopen", "vertclose", "horzopen", "horzclose",
+	"dissolve", "pixelize", "diagtl", "diagtr", "diagbl", "diagbr", "hlslice", "hrslice", "vuslice", "vdslice",
+	"hblur", "fadegrays", "wipetl", "wipetr", "wipebl", "wipebr", "squeezeh", "squeezev", "zoomin",
+	"fadefast", "fadeslow", "hlwind", "hrwind", "vuwind", "vdwind",
+	"coverleft", "coverright", "coverup", "coverdown", "revealleft", "revealright", "revealup", "revealdown",
+}
+
+// The named StoryBuilder transitions
+//
+//go:embed transitions.json
+var builtinTransitions []byte
+
+/* Structure of a named transition of a transitions registry file
+ *
+ *	Description: what the transition looks like
+ *	Xfade: the xfade transition it stands for
+ *	Expr: the expression of an xfade custom transition, using the variables A, B, X, Y, W, H, P and PLANE of xfade
+ */
+type Transition struct {
+	Description string `json:"description"`
+	Xfade       string `json:"xfade"`
+	Expr        string `json:"expr"`
+}
+
+/* Function to read the named StoryBuilder transitions and those of a transitions registry file
+ *
+ * Parameters:
+ *		registryPath - path to a JSON file of transitions by name, ignored if empty, its transitions replace the StoryBuilder ones of the same name
+ * Returns:
+ *		the transitions by name
+ *		err - error if a file cannot be read or a transition is invalid
+ */
+func ReadTransitions(registryPath string) (map[string]Transition, error) {
+	transitions := map[string]Transition{}
+	if err := json.Unmarshal(builtinTransitions, &transitions); err != nil {
+		return nil, err
+	}
+
+	if registryPath != "" {
+		data, err := os.ReadFile(registryPath)
+		if err != nil {
+			return nil, err
+		}
+		registry := map[string]Transition{}
+		if err := json.Unmarshal(data, &registry); err != nil {
+			return nil, fmt.Errorf("%s: %w", registryPath, err)
+		}
+		for name, transition := range registry {
+			transitions[name] = transition
+		}
+	}
+
+	for name, transition := range transitions {
+		if err := checkTransition(transition); err != nil {
+			return nil, fmt.Errorf("transition %q: %w", name, err)
+		}
+	}
+	return transitions, nil
+}
+
+/* Private function to check a named transition
+ *
+ * Parameters:
+ *		transition - the transition
+ * Returns:
+ *		an error if it is neither an xfade transition nor a valid custom expression
+ */
+func checkTransition(transition Transition) error {
+	if (transition.Xfade == "") == (transition.Expr == "") {
+		return fmt.Errorf("expected either an xfade transition or an expr")
+	}
+	if transition.Xfade != "" {
+		if !isXfadeTransition(transition.Xfade) {
+			return fmt.Errorf("unknown xfade transition %q", transition.Xfade)
+		}
+		return nil
+	}
+
+	// The expression is quoted in the filtergraph so it cannot contain quotes or end the filter
+	if strings.ContainsAny(transition.Expr, "'[];") {
+		return fmt.Errorf("expr %q cannot contain ', [, ] or ;", transition.Expr)
+	}
+	depth := 0
+	for _, char := range transition.Expr {
+		if char == '(' {
+			depth++
+		} else if char == ')' {
+			depth--
+		}
+		if depth < 0 {
+			break
+		}
+	}
+	if depth != 0 {
+		return fmt.Errorf("expr %q has unbalanced parentheses", transition.Expr)
+	}
+	return nil
+}
+
+/* Private function to check if a name is a transition of the xfade filter
+ *
+ * Parameters:
+ *		name - the name
+ * Returns:
+ *		true if xfade has the transition
+ */
+func isXfadeTransition(name string) bool {
+	for _, transition := range xfadeTransitions {
+		if name == transition {
+			return true
+		}
+	}
+	return false
+}
+
+/* Function to get the xfade options of a transition
+ *
+ * Parameters:
+ *		transitions - the named transitions (see ReadTransitions)
+ *		name - an xfade transition or a named transition, e.g. from <transition type>
+ * Returns:
+ *		the value of the xfade transition option, with the expression of custom transitions (e.g. "custom:expr='...'")
+ *		err - error if the transition is unknown
+ */
+func XfadeTransition(transitions map[string]Transition, name string) (string, error) {
+	if transition, found := transitions[name]; found {
+		if transition.Expr != "" {
+			return fmt.Sprintf("custom:expr='%s'", transition.Expr), nil
+		}
+		return transition.Xfade, nil
+	}
+	if isXfadeTransition(name) {
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown transition %q, expected an xfade transition or one of the transitions registry", name)
+}
@@ -2,7 +2,11 @@ package helper
 
 import (
 	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
 	"log"
+	"os"
 	"strconv"
 	"strings"
 )
@@ -36,3 +40,20 @@ func Check(err error) {
 		log.Fatalln(err)
 	}
 }
+
+/* Function to read an image
+ *  Parameters:
+ *			imagePath (string): path to the .jpg or .png image
+ *  Returns:
+ *			the decoded image and an error if it cannot be read
+ */
+func ReadImage(imagePath string) (image.Image, error) {
+	file, err := os.Open(imagePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	return img, err
+}
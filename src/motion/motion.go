@@ -0,0 +1,83 @@
+package motion
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Strategies of Generate
+var Strategies = []string{"zoomin", "zoomout", "pan", "random"}
+
+// Size of the zoomed in rectangle, as a fraction of the image, for a gentle motion
+const gentleZoom = 0.8
+
+/* Structure of a point of an image
+ *	X: horizontal position as a fraction of the width
+ *	Y: vertical position as a fraction of the height
+ */
+type Point struct {
+	X float64
+	Y float64
+}
+
+/* Function to get a rectangle of the image centred as close as possible on a point
+ *
+ * Parameters:
+ *		centre - the point
+ *		size - width and height of the rectangle as a fraction of the image
+ * Returns:
+ *		the rectangle as x, y, width and height fractions, within the image
+ */
+func rectangle(centre Point, size float64) []float64 {
+	x := math.Min(math.Max(centre.X-size/2, 0), 1-size)
+	y := math.Min(math.Max(centre.Y-size/2, 0), 1-size)
+	return []float64{round(x), round(y), round(size), round(size)}
+}
+
+/* Function to round a fraction like the motions of the templates
+ *
+ * Parameters:
+ *		value - the fraction
+ * Returns:
+ *		the fraction rounded to 3 decimals
+ */
+func round(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
+
+/* Function to generate a Ken Burns motion for a slide without one
+ *
+ * Parameters:
+ *		strategy - "zoomin" zooms gently into the focus, "zoomout" zooms out of it, "pan" pans across the
+ *			image toward the focus, "random" picks one of them with a random zoom and focus from the seed
+ *		focus - the point of the image the motion is centred on (see Focus)
+ *		seed - seed of the "random" strategy, the same seed always gives the same motion
+ * Returns:
+ *		the start and end rectangles, and an error if the strategy is unknown
+ */
+func Generate(strategy string, focus Point, seed int64) ([][]float64, error) {
+	size := gentleZoom
+	if strategy == "random" {
+		random := rand.New(rand.NewSource(seed))
+		strategy = Strategies[random.Intn(3)]
+		size = 0.75 + random.Float64()*0.15
+		focus = Point{focus.X + (random.Float64()-0.5)*0.2, focus.Y + (random.Float64()-0.5)*0.2}
+	}
+
+	switch strategy {
+	case "zoomin":
+		return [][]float64{{0, 0, 1, 1}, rectangle(focus, size)}, nil
+	case "zoomout":
+		return [][]float64{rectangle(focus, size), {0, 0, 1, 1}}, nil
+	case "pan":
+		// The pan starts on the opposite side of the image and ends on the focus
+		from := Point{1 - focus.X, 1 - focus.Y}
+		if math.Abs(focus.X-0.5) < 0.05 && math.Abs(focus.Y-0.5) < 0.05 {
+			// A centred focus would give no motion, so the pan goes from left to right through it
+			from, focus = Point{0, 0.5}, Point{1, 0.5}
+		}
+		return [][]float64{rectangle(from, size), rectangle(focus, size)}, nil
+	}
+	return nil, fmt.Errorf("unknown motion strategy %q, expected one of %v", strategy, Strategies)
+}
@@ -0,0 +1,65 @@
+package motion
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		focus    Point
+		want     [][]float64
+	}{
+		{"zoom in to the centre", "zoomin", Point{0.5, 0.5}, [][]float64{{0, 0, 1, 1}, {0.1, 0.1, 0.8, 0.8}}},
+		{"zoom out of a corner", "zoomout", Point{0.9, 0.1}, [][]float64{{0.2, 0, 0.8, 0.8}, {0, 0, 1, 1}}},
+		{"pan toward the focus", "pan", Point{0.7, 0.4}, [][]float64{{0, 0.2, 0.8, 0.8}, {0.2, 0, 0.8, 0.8}}},
+		{"pan through a centred focus", "pan", Point{0.5, 0.5}, [][]float64{{0, 0.1, 0.8, 0.8}, {0.2, 0.1, 0.8, 0.8}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Generate(tt.strategy, tt.focus, 1)
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Generate() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	first, _ := Generate("random", Point{0.5, 0.5}, 42)
+	again, _ := Generate("random", Point{0.5, 0.5}, 42)
+	if !reflect.DeepEqual(first, again) {
+		t.Errorf("Generate() with the same seed = %v then %v, want the same motion", first, again)
+	}
+	for _, rect := range first {
+		if rect[0] < 0 || rect[1] < 0 || rect[0]+rect[2] > 1.0005 || rect[1]+rect[3] > 1.0005 {
+			t.Errorf("Generate() random rectangle %v is outside the image", rect)
+		}
+	}
+
+	if _, err := Generate("spin", Point{0.5, 0.5}, 1); err == nil {
+		t.Errorf("Generate() with an unknown strategy should fail")
+	}
+}
+
+func TestFocus(t *testing.T) {
+	// Brightness fading from left to right, with a detailed checkerboard in the lower right corner
+	img := image.NewGray(image.Rect(0, 0, 320, 180))
+	for y := 0; y < 180; y++ {
+		for x := 0; x < 320; x++ {
+			img.SetGray(x, y, color.Gray{uint8(255 - x*255/320)})
+			if x >= 240 && y >= 120 && (x/10+y/10)%2 == 0 {
+				img.SetGray(x, y, color.Gray{255})
+			}
+		}
+	}
+
+	if focus := Focus(img, false); focus.X >= 0.5 {
+		t.Errorf("Focus() by brightness = %v, want the bright left side", focus)
+	}
+	if focus := Focus(img, true); focus.X <= 0.8 || focus.Y <= 0.75 {
+		t.Errorf("Focus() by saliency = %v, want the detailed lower right corner", focus)
+	}
+}
@@ -0,0 +1,122 @@
+package motion
+
+import (
+	"image"
+	"math"
+)
+
+// Width and height of the grid the energy of an image is computed on
+const gridSize = 64
+
+/* Function to sample the luma of an image on a small grid
+ *
+ * Parameters:
+ *		img - the image
+ * Returns:
+ *		the luma from 0 to 1 of each cell, by row
+ */
+func lumaGrid(img image.Image) [][]float64 {
+	bounds := img.Bounds()
+	grid := make([][]float64, gridSize)
+	for y := range grid {
+		grid[y] = make([]float64, gridSize)
+		for x := range grid[y] {
+			// The centre of the cell stands for the whole cell
+			px := bounds.Min.X + (2*x+1)*bounds.Dx()/(2*gridSize)
+			py := bounds.Min.Y + (2*y+1)*bounds.Dy()/(2*gridSize)
+			r, g, b, _ := img.At(px, py).RGBA()
+			grid[y][x] = (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 0xffff
+		}
+	}
+	return grid
+}
+
+/* Function to compute the edge energy of each cell of a luma grid with the Sobel operator, a cheap
+ * saliency map: detailed, contrasted areas have high energy and flat backgrounds have none
+ *
+ * Parameters:
+ *		luma - the luma grid (see lumaGrid)
+ * Returns:
+ *		the gradient magnitude of each cell, zero on the border
+ */
+func energy(luma [][]float64) [][]float64 {
+	size := len(luma)
+	grid := make([][]float64, size)
+	for y := range grid {
+		grid[y] = make([]float64, size)
+	}
+	for y := 1; y < size-1; y++ {
+		for x := 1; x < size-1; x++ {
+			gx := luma[y-1][x+1] + 2*luma[y][x+1] + luma[y+1][x+1] - luma[y-1][x-1] - 2*luma[y][x-1] - luma[y+1][x-1]
+			gy := luma[y+1][x-1] + 2*luma[y+1][x] + luma[y+1][x+1] - luma[y-1][x-1] - 2*luma[y-1][x] - luma[y-1][x+1]
+			grid[y][x] = math.Hypot(gx, gy)
+		}
+	}
+	return grid
+}
+
+/* Function to keep the cells of a grid above its mean, so a gentle gradient or noise over the whole image
+ * does not pull the centre of mass toward the middle
+ *
+ * Parameters:
+ *		grid - the weights by row
+ * Returns:
+ *		the weights minus their mean, zero for the cells below the mean
+ */
+func aboveMean(grid [][]float64) [][]float64 {
+	total, count := 0.0, 0
+	for _, row := range grid {
+		for _, weight := range row {
+			total += weight
+			count++
+		}
+	}
+	mean := total / float64(count)
+
+	kept := make([][]float64, len(grid))
+	for y, row := range grid {
+		kept[y] = make([]float64, len(row))
+		for x, weight := range row {
+			kept[y][x] = math.Max(weight-mean, 0)
+		}
+	}
+	return kept
+}
+
+/* Function to find the centre of mass of a grid of weights
+ *
+ * Parameters:
+ *		weights - the weight of each cell, by row
+ * Returns:
+ *		the centre of mass as fractions of the width and height, the centre of the grid if it has no weight
+ */
+func centreOfMass(weights [][]float64) Point {
+	total, sumX, sumY := 0.0, 0.0, 0.0
+	for y, row := range weights {
+		for x, weight := range row {
+			total += weight
+			sumX += weight * (float64(x) + 0.5)
+			sumY += weight * (float64(y) + 0.5)
+		}
+	}
+	if total == 0 {
+		return Point{0.5, 0.5}
+	}
+	return Point{sumX / total / float64(len(weights[0])), sumY / total / float64(len(weights))}
+}
+
+/* Function to find the point of an image the motion moves toward
+ *
+ * Parameters:
+ *		img - the image
+ *		saliency - whether to use the edge energy of the image (its detailed areas) rather than its brightness
+ * Returns:
+ *		the centre of mass of the energy or of the brightness, as fractions of the width and height
+ */
+func Focus(img image.Image, saliency bool) Point {
+	luma := lumaGrid(img)
+	if saliency {
+		return centreOfMass(aboveMean(energy(luma)))
+	}
+	return centreOfMass(luma)
+}
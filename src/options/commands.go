@@ -0,0 +1,211 @@
+package options
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+/* Function to check whether two paths name the same file
+ *  Parameters:
+ *			a, b (string) : the paths
+ *  Returns:
+ *			true if both paths lead to the same file, or to the same location when the files do not exist
+ */
+func isSameFile(a string, b string) bool {
+	aInfo, aErr := os.Stat(a)
+	bInfo, bErr := os.Stat(b)
+	if aErr == nil && bErr == nil {
+		return os.SameFile(aInfo, bInfo)
+	}
+	aAbs, aErr := filepath.Abs(a)
+	bAbs, bErr := filepath.Abs(b)
+	return aErr == nil && bErr == nil && aAbs == bAbs
+}
+
+type instantiateOptions struct {
+	TemplatePath  string
+	NarrationPath string
+	LabelsPath    string
+	OutputPath    string
+	Verbose       bool
+}
+
+/* Function to parse the command line options flags of the instantiate command
+ *  Parameters:
+ *			args ([]string) : the command line arguments after the command name
+ *  Returns:
+ *			initalized instantiateOptions struct
+ */
+func ParseInstantiateFlags(args []string) instantiateOptions {
+	var templatePath string
+	var narrationPath string
+	var labelsPath string
+	var outputPath string
+	var verbose bool
+
+	flags := flag.NewFlagSet("instantiate", flag.ExitOnError)
+	flags.StringVar(&templatePath, "t", "", "[filepath]: Template, the template .slideshow with <narration start> references and no narration timings")
+	flags.StringVar(&narrationPath, "audio", "", "[filepath]: Narration, the narration audio of the story in the language")
+	flags.StringVar(&labelsPath, "labels", "", "[filepath]: Verse Timestamps, Audacity label track or SAB timing file with the start of each verse in the narration")
+	flags.StringVar(&outputPath, "o", "", "[filepath]: Output, the .slideshow to write (default is the template filename next to the narration audio)")
+	flags.BoolVar(&verbose, "v", false, "(boolean): Verbose, include to print the timing of each slide")
+	flags.Parse(args)
+
+	if templatePath == "" || narrationPath == "" || labelsPath == "" {
+		log.Fatalf("instantiate needs -t, -audio and -labels")
+	}
+	if outputPath == "" {
+		outputPath = path.Join(path.Dir(narrationPath), path.Base(templatePath))
+		if isSameFile(outputPath, templatePath) {
+			log.Fatalf("the narration audio is next to the template, use -o to write the .slideshow without overwriting %s", templatePath)
+		}
+	}
+
+	options := instantiateOptions{templatePath, narrationPath, labelsPath, outputPath, verbose}
+
+	return options
+}
+
+type suggestOptions struct {
+	TemplatePath  string
+	NarrationPath string
+	OutputPath    string
+	Target        float64
+	Noise         float64
+	MinPause      float64
+	Verbose       bool
+}
+
+/* Function to parse the command line options flags of the suggest command
+ *  Parameters:
+ *			args ([]string) : the command line arguments after the command name
+ *  Returns:
+ *			initalized suggestOptions struct, with the target and minimum pause in seconds
+ */
+func ParseSuggestFlags(args []string) suggestOptions {
+	var templatePath string
+	var narrationPath string
+	var outputPath string
+	var target float64
+	var noise float64
+	var minPause float64
+	var verbose bool
+
+	flags := flag.NewFlagSet("suggest", flag.ExitOnError)
+	flags.StringVar(&templatePath, "t", "", "[filepath]: Template, the template .slideshow to write the suggested timings of its narrated slides to (without it the suggested timings are printed)")
+	flags.StringVar(&narrationPath, "audio", "", "[filepath]: Narration, the narration audio of the story")
+	flags.StringVar(&outputPath, "o", "", "[filepath]: Output, the .slideshow to write (default is the template filename next to the narration audio)")
+	flags.Float64Var(&target, "target", 8000, "[milliseconds]: Target, length wanted for each slide when there is no template")
+	flags.Float64Var(&noise, "noise", -35, "[dB]: Noise, level below which the narration counts as silence")
+	flags.Float64Var(&minPause, "pause", 300, "[milliseconds]: Pause, shortest pause a slide can start at")
+	flags.BoolVar(&verbose, "v", false, "(boolean): Verbose, include to print the timing of each slide")
+	flags.Parse(args)
+
+	if narrationPath == "" {
+		log.Fatalf("suggest needs -audio")
+	}
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "target" && templatePath != "" {
+			log.Fatalf("-target cannot be used with -t, the number of slides comes from the template")
+		}
+	})
+	if target <= 0 || minPause <= 0 {
+		log.Fatalf("-target and -pause must be greater than 0")
+	}
+	if outputPath == "" && templatePath != "" {
+		outputPath = path.Join(path.Dir(narrationPath), path.Base(templatePath))
+		if isSameFile(outputPath, templatePath) {
+			log.Fatalf("the narration audio is next to the template, use -o to write the .slideshow without overwriting %s", templatePath)
+		}
+	}
+
+	options := suggestOptions{templatePath, narrationPath, outputPath, target / 1000, noise, minPause / 1000, verbose}
+
+	return options
+}
+
+type sabLogOptions struct {
+	SlideshowPath string
+	LogPath       string
+	OutputPath    string
+	Verbose       bool
+}
+
+/* Function to parse the command line options flags of the sablog command
+ *  Parameters:
+ *			args ([]string) : the command line arguments after the command name
+ *  Returns:
+ *			initalized sabLogOptions struct
+ */
+func ParseSABLogFlags(args []string) sabLogOptions {
+	var slideshowPath string
+	var logPath string
+	var outputPath string
+	var verbose bool
+
+	flags := flag.NewFlagSet("sablog", flag.ExitOnError)
+	flags.StringVar(&slideshowPath, "t", "", "[filepath]: Template, the .slideshow produced by Scripture App Builder")
+	flags.StringVar(&logPath, "log", "", "[filepath]: Log, the SAB log with the timing of each slide (default is sab.log next to the .slideshow)")
+	flags.StringVar(&outputPath, "o", "", "[filepath]: Output, rebuilds the .slideshow with the timings of the log into this file instead of verifying it")
+	flags.BoolVar(&verbose, "v", false, "(boolean): Verbose, include to print the timing of each slide")
+	flags.Parse(args)
+
+	if slideshowPath == "" {
+		log.Fatalf("sablog needs -t")
+	}
+	if logPath == "" {
+		logPath = path.Join(path.Dir(slideshowPath), "sab.log")
+	}
+
+	options := sabLogOptions{slideshowPath, logPath, outputPath, verbose}
+
+	return options
+}
+
+type initOptions struct {
+	Directory  string
+	AudioPath  string
+	Duration   float64
+	OutputPath string
+	Verbose    bool
+}
+
+/* Function to parse the command line options flags of the init command
+ *  Parameters:
+ *			args ([]string) : the command line arguments after the command name
+ *  Returns:
+ *			initalized initOptions struct
+ */
+func ParseInitFlags(args []string) initOptions {
+	var directory string
+	var audioPath string
+	var duration float64
+	var outputPath string
+	var verbose bool
+
+	flags := flag.NewFlagSet("init", flag.ExitOnError)
+	flags.StringVar(&directory, "d", ".", "[filepath]: Directory, the folder with the images of the story")
+	flags.StringVar(&audioPath, "audio", "", "[filepath]: Narration, audio whose length is split evenly between the slides (default is the only audio in the folder, if there is one)")
+	flags.Float64Var(&duration, "duration", 5000, "[milliseconds]: Duration, length of each slide when there is no narration")
+	flags.StringVar(&outputPath, "o", "", "[filepath]: Output, the .slideshow to write (default is the folder name .slideshow in the folder)")
+	flags.BoolVar(&verbose, "v", false, "(boolean): Verbose, include to print the image and timing of each slide")
+	flags.Parse(args)
+
+	if duration <= 0 {
+		log.Fatalf("-duration must be greater than 0")
+	}
+	if outputPath == "" {
+		abs, err := filepath.Abs(directory)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		outputPath = filepath.Join(directory, filepath.Base(abs)+".slideshow")
+	}
+
+	options := initOptions{directory, audioPath, duration, outputPath, verbose}
+
+	return options
+}
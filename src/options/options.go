@@ -2,8 +2,14 @@ package options
 
 import (
 	"flag"
+	"log"
+	"strconv"
+	"strings"
 )
 
+// Default output filename template, the .slideshow filename with an .mp4 extension
+const defaultOutputTemplate = "{template}.mp4"
+
 type options struct {
 	SlideshowDirectory    string
 	OutputDirectory       string
@@ -13,13 +19,51 @@ type options struct {
 	SaveTemps             bool
 	UseOldFade            bool
 	Verbose               bool
+	RenderCards           bool
+	CardFonts             []string
+	CardBackground        string
+	CardLayout            string
+	CreditsFile           string
+	Lang                  string
+	OutputTemplate        string
+	MetadataFile          string
+	Chapters              string
+	Poster                string
+	Thumbnails            bool
+	ContactSheet          bool
+	Preview               string
+	HLS                   bool
+	DASH                  bool
+	Renditions            []int
+	Subtitles             string
+	AudioOnly             string
+	Loudnorm              bool
+	LoudnessTarget        float64
+	TruePeak              float64
+	LoudnessRange         float64
+	Report                string
+	NoDucking             bool
+	DuckThreshold         float64
+	DuckRatio             float64
+	DuckAttack            float64
+	DuckRelease           float64
+	MusicFadeIn           float64
+	MusicFadeOut          float64
+	ClickFade             float64
+	AudioCrossfade        bool
+	Motion                string
+	MotionSeed            int64
+	Saliency              bool
+	Renderer              string
 }
 
-/* Function to parse the command line options flags
+/* Function to parse the command line options flags of the render command
+ *  Parameters:
+ *			args ([]string) : the command line arguments after the command name
  *  Returns:
  *			initalized options struct
  */
-func ParseFlags() options {
+func ParseFlags(args []string) options {
 	var slideshowDirectory string
 	var outputDirectory string
 	var temporaryDirectory string
@@ -28,19 +72,130 @@ func ParseFlags() options {
 	var saveTemps bool
 	var useOldFade bool
 	var verbose bool
+	var renderCards bool
+	var cardFonts string
+	var cardBackground string
+	var cardLayout string
+	var creditsFile string
+	var lang string
+	var outputTemplate string
+	var metadataFile string
+	var chapters string
+	var poster string
+	var thumbnails bool
+	var contactSheet bool
+	var preview string
+	var hls bool
+	var dash bool
+	var renditions string
+	var subtitles string
+	var audioOnly string
+	var loudnorm bool
+	var loudnessTarget float64
+	var truePeak float64
+	var loudnessRange float64
+	var report string
+	var noDucking bool
+	var duckThreshold float64
+	var duckRatio float64
+	var duckAttack float64
+	var duckRelease float64
+	var musicFadeIn float64
+	var musicFadeOut float64
+	var clickFade float64
+	var audioCrossfade bool
+	var motion string
+	var motionSeed int64
+	var saliency bool
+	var renderer string
 
 	flag.BoolVar(&lowQuality, "l", false, "(boolean): Low Quality, include to generate a lower quality video (480p instead of 720p)")
 	flag.BoolVar(&saveTemps, "s", false, "(boolean): Save Temporaries, include to save temporary files generated during video process)")
 	flag.BoolVar(&useOldFade, "f", false, "(boolean): Fadetype, include to use the non-xfade default transitions for video")
 	flag.BoolVar(&verbose, "v", false, "(boolean): Verbose, include to increase the verbosity of the feedback provided")
+	flag.BoolVar(&thumbnails, "thumbs", false, "(boolean): Thumbnails, include to export a thumbnail of each slide next to the final video")
+	flag.BoolVar(&contactSheet, "contactsheet", false, "(boolean): Contact Sheet, include to export a grid image of all the slides next to the final video for review")
+	flag.BoolVar(&hls, "hls", false, "(boolean): HLS, include to create an HLS ladder (segments and master playlist) next to the final video")
+	flag.BoolVar(&dash, "dash", false, "(boolean): DASH, include to create a DASH manifest and segments next to the final video")
+	flag.BoolVar(&renderCards, "cards", false, "(boolean): Render Cards, include to draw the title and credits slides from the slideshow titles instead of using converted .odg images")
 
 	flag.StringVar(&slideshowDirectory, "t", "", "[filepath]: Template Name, specify a template to use (if not included searches current folder for template)")
 	flag.StringVar(&outputDirectory, "o", "", "[filepath]: Output Location, specify where to store final result (default is current directory)")
 	flag.StringVar(&temporaryDirectory, "td", "", "[filepath]: Temporary Directory, used to specify a location to store the temporary files used in video production (default is OS' temp folder/storybuilder-*)")
 	flag.StringVar(&overlayVideoDirectory, "ov", "", "[filepath]: Overlay Video, specify test video location to create overlay video")
-	flag.Parse()
+	flag.StringVar(&cardFonts, "font", "", "[filepath,...]: Card Fonts, TTF/OTF fonts for the rendered cards, later fonts are used for characters missing from earlier ones (default is the bundled Go font)")
+	flag.StringVar(&cardBackground, "cardbg", "", "[filepath|#rrggbb]: Card Background, image or colour behind the text of rendered cards (default is black)")
+	flag.StringVar(&cardLayout, "cardlayout", "centered", "[centered|lower-third]: Card Layout, layout template for the rendered title card")
+	flag.StringVar(&creditsFile, "credits", "", "[filepath]: Credits, text file with the credits to draw on the rendered credits card")
+	flag.StringVar(&lang, "lang", "", "[code]: Language, language code (e.g. en, fr) used to choose the title and images tagged with a lang attribute (default is the untagged ones)")
+	flag.StringVar(&outputTemplate, "name", defaultOutputTemplate, "[template]: Output Name, filename template for the final video using {title}, {lang}, {story}, {template}, {date} and {resolution} (e.g. {lang}/{title}-{resolution}.mp4)")
+	flag.StringVar(&metadataFile, "meta", "", "[filepath]: Metadata, JSON file of MP4 metadata tags (artist, publisher, copyright, comment, ...) to write to the final video")
+	flag.StringVar(&chapters, "chapters", "", "[slide|verse]: Chapters, write chapter markers at each slide or at each <narration start> verse reference (default is no chapters)")
+	flag.StringVar(&poster, "poster", "", "[slide:N|seconds]: Poster, export a poster frame from the middle of slide N or at a time in seconds next to the final video")
+	flag.StringVar(&preview, "preview", "", "[seconds|slides:N,M-O]: Preview, create a short silent teaser (MP4, GIF and WebP) of the first seconds or of chosen slides instead of the full video")
+	flag.StringVar(&renditions, "renditions", "360,480,720", "[height,...]: Renditions, pixel heights of the HLS/DASH renditions")
+	flag.StringVar(&subtitles, "subtitles", "", "[filepath]: Subtitles, WebVTT file for the HLS subtitle rendition (default is the .vtt next to the .slideshow, if any)")
+	flag.StringVar(&audioOnly, "audioonly", "", "[mp3|m4a|opus]: Audio Only, export only the narration and background music plus a JSON timeline of the slides instead of the video")
+	flag.BoolVar(&loudnorm, "loudnorm", false, "(boolean): Loudness Normalization, include to normalize the final mix to an EBU R128 target with two loudnorm passes")
+	flag.Float64Var(&loudnessTarget, "lufs", -16, "[LUFS]: Loudness Target, integrated loudness target of -loudnorm")
+	flag.Float64Var(&truePeak, "truepeak", -1.5, "[dBTP]: True Peak, maximum true peak of -loudnorm")
+	flag.Float64Var(&loudnessRange, "lra", 11, "[LU]: Loudness Range, loudness range target of -loudnorm")
+	flag.StringVar(&report, "report", "", "[filepath]: Render Report, write a JSON report of the render (output, slide timings, measured loudness, time taken)")
+	flag.BoolVar(&noDucking, "noduck", false, "(boolean): No Ducking, include to mix background music at equal weight instead of lowering it under the narration")
+	flag.Float64Var(&duckThreshold, "duckthreshold", -30, "[dB]: Ducking Threshold, narration level above which background music is lowered")
+	flag.Float64Var(&duckRatio, "duckratio", 8, "[1-20]: Ducking Ratio, how much background music is lowered under the narration")
+	flag.Float64Var(&duckAttack, "duckattack", 20, "[ms]: Ducking Attack, time to lower background music once the narration starts")
+	flag.Float64Var(&duckRelease, "duckrelease", 400, "[ms]: Ducking Release, time to bring background music back once the narration stops")
+	flag.Float64Var(&musicFadeIn, "musicfadein", 1000, "[ms]: Music Fade In, length of the fade-in where the background music starts")
+	flag.Float64Var(&musicFadeOut, "musicfadeout", 2000, "[ms]: Music Fade Out, length of the fade-out where the background music stops")
+	flag.Float64Var(&clickFade, "clickfade", 10, "[ms]: Click Fade, length of the short fades at each audio cut that prevent clicks (0 for hard cuts)")
+	flag.BoolVar(&audioCrossfade, "audiocrossfade", false, "(boolean): Audio Crossfade, include to crossfade the audio of consecutive slides over their transition so audio and video transitions match")
+	flag.StringVar(&motion, "motion", "", "[zoomin|zoomout|pan|random]: Motion, Ken Burns motion generated for the narrated slides without a <motion> (default is to keep them still)")
+	flag.Int64Var(&motionSeed, "motionseed", 1, "[number]: Motion Seed, seed of the random -motion, the same seed gives the same motions on every render")
+	flag.BoolVar(&saliency, "saliency", false, "(boolean): Saliency, include to centre the generated motions on the detailed areas of the images instead of their brightness")
+	flag.StringVar(&renderer, "renderer", "ffmpeg", "[ffmpeg|go]: Renderer, renderer of the zoom/pan effects, ffmpeg's zoompan filter or frames computed in Go and piped to ffmpeg (faster, uses less memory)")
+	flag.CommandLine.Parse(args)
+
+	if chapters != "" && chapters != "slide" && chapters != "verse" {
+		log.Fatalf("invalid -chapters value %q, expected slide or verse", chapters)
+	}
+
+	switch motion {
+	case "", "zoomin", "zoomout", "pan", "random":
+	default:
+		log.Fatalf("invalid -motion value %q, expected zoomin, zoomout, pan or random", motion)
+	}
+
+	if renderer != "ffmpeg" && renderer != "go" {
+		log.Fatalf("invalid -renderer value %q, expected ffmpeg or go", renderer)
+	}
+
+	if duckRatio < 1 || duckRatio > 20 {
+		log.Fatalf("invalid -duckratio value %g, expected 1 to 20", duckRatio)
+	}
+
+	heights := []int{}
+	for _, rendition := range strings.Split(renditions, ",") {
+		height, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(rendition), "p"))
+		if err != nil || height <= 0 {
+			log.Fatalf("invalid -renditions value %q, expected heights like 360,480,720", renditions)
+		}
+		heights = append(heights, height)
+	}
+
+	fonts := []string{}
+	if cardFonts != "" {
+		fonts = strings.Split(cardFonts, ",")
+	}
 
-	options := options{slideshowDirectory, outputDirectory, temporaryDirectory, overlayVideoDirectory, lowQuality, saveTemps, useOldFade, verbose}
+	options := options{slideshowDirectory, outputDirectory, temporaryDirectory, overlayVideoDirectory, lowQuality, saveTemps, useOldFade, verbose,
+		renderCards, fonts, cardBackground, cardLayout, creditsFile, lang,
+		outputTemplate, metadataFile, chapters, poster, thumbnails, contactSheet, preview,
+		hls, dash, heights, subtitles, audioOnly,
+		loudnorm, loudnessTarget, truePeak, loudnessRange, report,
+		noDucking, duckThreshold, duckRatio, duckAttack, duckRelease,
+		musicFadeIn, musicFadeOut, clickFade, audioCrossfade,
+		motion, motionSeed, saliency, renderer}
 
 	return options
 
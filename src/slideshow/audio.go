@@ -0,0 +1,175 @@
+package slideshow
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	FFmpeg "github.com/sillsdev/appbuilder-storybuilder/src/ffmpeg"
+)
+
+/* Function to find the fades of the background music of a slide
+ *
+ * Parameters:
+ *		i - index of the slide
+ *		fades - the fades chosen for the slideshow
+ * Returns:
+ *		the music fade-in if the music starts on the slide and its fade-out if it stops at the end of the slide
+ */
+func (s slideshow) musicFade(i int, fades FFmpeg.AudioFades) FFmpeg.Fade {
+	fade := FFmpeg.Fade{}
+	if s.mixes[i].music == "" {
+		return fade
+	}
+	if i == 0 || s.mixes[i-1].music != s.mixes[i].music {
+		fade.In = fades.MusicIn
+	}
+	if i == len(s.mixes)-1 || s.mixes[i+1].music != s.mixes[i].music {
+		fade.Out = fades.MusicOut
+	}
+	return fade
+}
+
+/* Function to find the fades of the audio of each slide
+ *
+ * Parameters:
+ *		fades - the fades chosen for the slideshow
+ * Returns:
+ *		the fades of each slide, the short anti-click fades at each cut or the music fades of slides with only music
+ */
+func (s slideshow) audioFades(fades FFmpeg.AudioFades) []FFmpeg.Fade {
+	slideFades := []FFmpeg.Fade{}
+	for i := range s.audios {
+		if s.audios[i] == "" {
+			slideFades = append(slideFades, FFmpeg.Fade{})
+			continue
+		}
+		fade := FFmpeg.Fade{In: fades.Click, Out: fades.Click}
+		if s.mixes[i].music != "" && s.mixes[i].narration == "" {
+			music := s.musicFade(i, fades)
+			if music.In > fade.In {
+				fade.In = music.In
+			}
+			if music.Out > fade.Out {
+				fade.Out = music.Out
+			}
+		}
+		slideFades = append(slideFades, fade)
+	}
+	return slideFades
+}
+
+/* Function to find where the audio of each slide is read from. Each source file is read on from where the
+ * previous slide using it stopped, so a narration continues across slides even when it is interleaved with
+ * other narrations or mixed under background music on some of them.
+ *
+ * Returns:
+ *		narration - time in seconds the narration (or the only audio) of each slide starts within its file
+ *		music - time in seconds the background music mixed under the narration of each slide starts within its file
+ */
+func (s slideshow) sourcePositions() ([]float64, []float64) {
+	narration := make([]float64, len(s.audios))
+	music := make([]float64, len(s.audios))
+	durations := s.timingSeconds()
+	positions := map[string]float64{}
+
+	for i := range s.audios {
+		source := s.audios[i]
+		if s.mixes[i].narration != "" {
+			source = s.mixes[i].narration
+			music[i] = positions[s.mixes[i].music]
+			positions[s.mixes[i].music] += durations[i]
+		}
+		if source == "" {
+			continue
+		}
+		narration[i] = positions[source]
+		positions[source] += durations[i]
+	}
+	return narration, music
+}
+
+/* Function to get the length of a slide's transition in seconds
+ *
+ * Parameters:
+ *		i - index of the slide
+ * Returns:
+ *		the duration of the transition from the slide into the next one
+ */
+func (s slideshow) transitionSeconds(i int) float64 {
+	duration, err := strconv.ParseFloat(strings.TrimSpace(s.transitionDurations[i]), 64)
+	if err != nil {
+		return 0
+	}
+	return duration / 1000
+}
+
+/* Function to lay out the audio of the slides on the output timeline. Slide i is placed where it starts in
+ * the video (see FFmpeg.SlideOffsets) and, when crossfading, runs on under the part of the transition into the
+ * next slide after its start, while the next slide starts early under the part before (see FFmpeg.TransitionLead).
+ *
+ * Parameters:
+ *		fades - the fades chosen for the slideshow
+ * Returns:
+ *		the audio clips in output order, one for each slide
+ */
+func (s slideshow) audioTimeline(fades FFmpeg.AudioFades) []FFmpeg.AudioClip {
+	offsets := FFmpeg.SlideOffsets(s.timingSeconds())
+	positions, _ := s.sourcePositions()
+	slideFades := s.audioFades(fades)
+
+	clips := []FFmpeg.AudioClip{}
+	for i, audio := range s.audios {
+		// Slides without audio get silence so the audio spans the whole video
+		clip := FFmpeg.AudioClip{Source: audio, SourceStart: positions[i], Start: offsets[i], Duration: offsets[i+1] - offsets[i], Fade: slideFades[i]}
+		if s.mixes[i].narration != "" {
+			// The mixed audio of the slide starts with its part of the narration and music
+			clip.SourceStart = 0
+		}
+		if fades.Crossfade && i > 0 {
+			// The audio before the start of the slide, as far as its source goes back
+			lead := math.Min(FFmpeg.TransitionLead(s.transitionSplits[i-1], s.transitionSeconds(i-1)), clip.SourceStart)
+			clip.Start -= lead
+			clip.SourceStart -= lead
+			clip.Duration += lead
+		}
+		if fades.Crossfade && i < len(s.audios)-1 {
+			transition := s.transitionSeconds(i)
+			clip.Duration += transition - FFmpeg.TransitionLead(s.transitionSplits[i], transition)
+		}
+		clips = append(clips, clip)
+	}
+	return clips
+}
+
+/* Function to mix the background music under the narration of the slides that have both
+ *
+ * Parameters:
+ *			ducking - sidechain compression lowering the music while the narration is speaking, nil to mix at equal weight
+ *			fades - fades of the background music
+ *			v - verbose flag to determine what feedback to print
+ */
+func (s slideshow) MixMusic(ducking *FFmpeg.Ducking, fades FFmpeg.AudioFades, v bool) {
+	narration, music := s.sourcePositions()
+	for i, mix := range s.mixes {
+		if mix.narration == "" {
+			continue
+		}
+		if v {
+			fmt.Printf("Mixing background music %s under the narration of slide %d...\n", mix.music, i+1)
+		}
+		FFmpeg.MergeAudios(mix.music, mix.narration, milliseconds(music[i]), milliseconds(narration[i]), s.timings[i], ducking, s.musicFade(i, fades), s.audios[i])
+	}
+}
+
+/* Function to format a time for the ffmpeg functions taking milliseconds
+ *
+ * Parameters:
+ *		seconds - the time in seconds
+ * Returns:
+ *		the time in milliseconds
+ */
+func milliseconds(seconds float64) string {
+	return strconv.FormatFloat(seconds*1000, 'f', -1, 64)
+}
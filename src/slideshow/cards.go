@@ -0,0 +1,61 @@
+package slideshow
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+
+	"github.com/sillsdev/appbuilder-storybuilder/src/card"
+)
+
+// Regular expressions to find the title and credits images (see convert-rules.json)
+var titleImageRegEx = regexp.MustCompile(`-title(-[^./\\]+)?\.[^./\\]+$`)
+var creditsImageRegEx = regexp.MustCompile(`-credits(-[^./\\]+)?\.[^./\\]+$`)
+
+/* Function to render the title and credits slides with Go instead of using the images
+ * converted from the .odg files
+ *
+ * Parameters:
+ *		fontPaths - fonts to draw the text with, in fallback order (bundled font if empty)
+ *		background - background image or "#rrggbb" colour of the cards
+ *		titleLayout - layout template for the title card, centered or lower-third
+ *		creditsText - text to draw on the credits card, the credits slide is left untouched if empty
+ *		v - verbose flag to determine what feedback to print
+ * Returns:
+ *		err - error if the title layout is unknown or a card cannot be rendered, nil if successful
+ */
+func (s slideshow) RenderCards(fontPaths []string, background string, titleLayout string, creditsText string, v bool) error {
+	if !card.IsTitleLayout(titleLayout) {
+		return fmt.Errorf("invalid -cardlayout value %q, expected centered or lower-third", titleLayout)
+	}
+
+	cardDirectory := path.Join(s.tempPath, "cards")
+	if err := os.MkdirAll(cardDirectory, os.ModePerm); err != nil {
+		return err
+	}
+
+	for i, image := range s.images {
+		text := ""
+		layout := ""
+		if titleImageRegEx.MatchString(image) {
+			text, layout = s.title, titleLayout
+		} else if creditsImageRegEx.MatchString(image) {
+			text, layout = creditsText, "credits"
+		}
+		if text == "" {
+			continue
+		}
+
+		output := path.Join(cardDirectory, fmt.Sprintf("card-%d.jpg", i))
+		if v {
+			fmt.Printf("Rendering %s card for slide %d to %s\n", layout, i, output)
+		}
+		if err := card.Render(text, fontPaths, background, layout, 1280, 720, output); err != nil {
+			return err
+		}
+		s.images[i] = output
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package slideshow
+
+import (
+	"fmt"
+	"strings"
+
+	FFmpeg "github.com/sillsdev/appbuilder-storybuilder/src/ffmpeg"
+)
+
+/* Function to format a verse reference like "JHN.1.1" as "JHN 1:1"
+ *
+ * Parameters:
+ *		reference - the reference from the <narration> element
+ * Returns:
+ *		the reference formatted for display
+ */
+func formatReference(reference string) string {
+	parts := strings.Split(reference, ".")
+	if len(parts) != 3 {
+		return reference
+	}
+	return fmt.Sprintf("%s %s:%s", parts[0], parts[1], parts[2])
+}
+
+/* Function to find the title of the chapter starting at a slide
+ *
+ * Parameters:
+ *		i - index of the slide
+ * Returns:
+ *		the <chapter> label, the formatted narration reference, or a generic label
+ */
+func (s slideshow) chapterTitle(i int) string {
+	if s.chapterLabels[i] != "" {
+		return s.chapterLabels[i]
+	}
+	if s.narrations[i] != "" {
+		return formatReference(s.narrations[i])
+	}
+	if titleImageRegEx.MatchString(s.images[i]) && s.title != "" {
+		return s.title
+	}
+	if creditsImageRegEx.MatchString(s.images[i]) {
+		return "Credits"
+	}
+	return fmt.Sprintf("Slide %d", i+1)
+}
+
+/* Function to create the chapter markers of the video
+ *
+ * Parameters:
+ *		offsets - start time of each slide in seconds followed by the total length (see FFmpeg.SlideOffsets)
+ *		mode - "slide" for a chapter per slide, "verse" to group slides by their <narration start> reference
+ * Returns:
+ *		the chapters in order
+ */
+func (s slideshow) chapters(offsets []float64, mode string) []FFmpeg.Chapter {
+	chapters := []FFmpeg.Chapter{}
+	for i := range s.images {
+		// In verse mode a slide continues the previous chapter if it continues the narration without a new reference
+		continues := mode == "verse" && i > 0 && s.narrations[i] == "" && s.chapterLabels[i] == "" &&
+			s.audios[i] != "" && s.audios[i] == s.audios[i-1]
+		if continues {
+			chapters[len(chapters)-1].End = offsets[i+1]
+			continue
+		}
+		chapters = append(chapters, FFmpeg.Chapter{Start: offsets[i], End: offsets[i+1], Title: s.chapterTitle(i)})
+	}
+	return chapters
+}
+
+/* Function to write the chapter markers to the temp folder
+ *
+ * Parameters:
+ *		offsets - start time of each slide in seconds followed by the total length
+ *		mode - "slide", "verse", or empty for no chapters
+ *		tempPath - path to the temp folder
+ * Returns:
+ *		path to the FFMETADATA chapters file, empty if no chapters are written
+ */
+func (s slideshow) writeChapters(offsets []float64, mode string, tempPath string) string {
+	if mode == "" {
+		return ""
+	}
+	return FFmpeg.WriteChapters(s.chapters(offsets, mode), tempPath)
+}
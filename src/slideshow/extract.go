@@ -0,0 +1,151 @@
+package slideshow
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	FFmpeg "github.com/sillsdev/appbuilder-storybuilder/src/ffmpeg"
+	Timing "github.com/sillsdev/appbuilder-storybuilder/src/timing"
+)
+
+/* Structure of a narration segment cut out of a longer audio, e.g. a chapter audio
+ *	source: filepath to the longer audio
+ *	start: where the segment starts, a time (seconds or hh:mm:ss.mmm) or a verse reference (BOOK.c.v), empty for the start of the audio
+ *	end: where the segment ends, a time or a verse reference, empty for the end of the audio
+ *	labels: filepath to the verse timestamps of the audio, needed for verse references
+ *	output: filepath to the extracted WAV segment
+ */
+type audioExtract struct {
+	source string
+	start  string
+	end    string
+	labels string
+	output string
+}
+
+/* Function to add a narration segment to extract, reusing the segment of an earlier slide if it is the same
+ *
+ * Parameters:
+ *		extracts - the segments to extract, the new one is appended
+ *		extract - the segment, its output is set here
+ *		tempPath - path to the temp folder
+ * Returns:
+ *		the filepath to the extracted segment
+ */
+func addExtract(extracts *[]audioExtract, extract audioExtract, tempPath string) string {
+	for _, existing := range *extracts {
+		if existing.source == extract.source && existing.start == extract.start && existing.end == extract.end && existing.labels == extract.labels {
+			return existing.output
+		}
+	}
+	extract.output = path.Join(tempPath, fmt.Sprintf("narration-%02d.wav", len(*extracts)+1))
+	*extracts = append(*extracts, extract)
+	return extract.output
+}
+
+/* Function to get the filepath of the verse timestamps named in a <filename labels> attribute
+ *
+ * Parameters:
+ *		templateDir - folder path leading up to the .slideshow file
+ *		labels - the attribute, relative to the .slideshow
+ * Returns:
+ *		the filepath, empty if there is no attribute
+ */
+func labelsPath(templateDir string, labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return templateDir + labels
+}
+
+/* Function to find the start and end time of a narration segment in its audio
+ *
+ * Parameters:
+ *		audioDuration - function returning the length in seconds of the audio, only called if needed
+ * Returns:
+ *		the start and end time in seconds, and an error if a reference cannot be found
+ */
+func (e audioExtract) span(audioDuration func() float64) (float64, float64, error) {
+	start, err := e.position(e.start, false, audioDuration)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := e.position(e.end, true, audioDuration)
+	return start, end, err
+}
+
+/* Function to find a time in the audio of a narration segment
+ *
+ * Parameters:
+ *		value - a time (seconds or hh:mm:ss.mmm) or a verse reference starting with the book (BOOK.c.v)
+ *		atEnd - whether the value is the end of the segment, a verse reference then stands for the end of the verse
+ *		audioDuration - function returning the length in seconds of the audio, only called if needed
+ * Returns:
+ *		the time in seconds, the start or end of the audio if the value is empty, and an error if a reference cannot be found
+ */
+func (e audioExtract) position(value string, atEnd bool, audioDuration func() float64) (float64, error) {
+	if value == "" && atEnd {
+		return audioDuration(), nil
+	}
+	if value == "" {
+		return 0, nil
+	}
+	// Only references starting with the book are verses, so 1:5 is a time (65 seconds) and not verse 5
+	value = strings.TrimSpace(value)
+	if first, _ := utf8.DecodeRuneInString(value); !unicode.IsLetter(first) {
+		seconds, err := Timing.ParseTime(value)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w, verse references are written BOOK.c.v, e.g. JHN.1.5", e.source, err)
+		}
+		return seconds, nil
+	}
+
+	// Verse references are looked up in the verse timestamps of the audio
+	if e.labels == "" {
+		return 0, fmt.Errorf("%s: the narration is cut at %s but <filename> has no labels attribute", e.source, value)
+	}
+	labels, err := Timing.ReadLabels(e.labels)
+	if err != nil {
+		return 0, err
+	}
+	start, end, err := Timing.VerseSpan(labels, value, value, audioDuration())
+	if atEnd {
+		return end, err
+	}
+	return start, err
+}
+
+/* Function to cut the narration of the slides out of longer audios, for slides whose <filename> has a start
+ * or end attribute
+ *
+ * Parameters:
+ *		v - verbose flag to determine what feedback to print
+ * Returns:
+ *		err - error if a segment cannot be found, nil if successful
+ */
+func (s slideshow) ExtractNarration(v bool) error {
+	for _, extract := range s.extracts {
+		source := extract.source
+		duration := -1.0
+		start, end, err := extract.span(func() float64 {
+			if duration < 0 {
+				duration = FFmpeg.GetVideoLength(source)
+			}
+			return duration
+		})
+		if err != nil {
+			return err
+		}
+		if end <= start {
+			return fmt.Errorf("%s: the narration ends at %.3f seconds, before it starts at %.3f", source, end, start)
+		}
+		if v {
+			fmt.Printf("Extracting %.3f to %.3f seconds of %s...\n", start, end, source)
+		}
+		FFmpeg.ExtractAudio(source, start, end, extract.output)
+	}
+	return nil
+}
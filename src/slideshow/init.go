@@ -0,0 +1,204 @@
+package slideshow
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	FFmpeg "github.com/sillsdev/appbuilder-storybuilder/src/ffmpeg"
+)
+
+// File extensions of the images and audios picked up by Init
+var imageExtensions = []string{".jpg", ".jpeg", ".png"}
+var audioExtensions = []string{".mp3", ".wav", ".m4a", ".ogg", ".opus", ".flac"}
+
+// Ken Burns motions given in turn to the slides created by Init: zoom in, zoom out, pan right and pan left
+var defaultMotions = [][2]string{
+	{"0 0 1 1", "0.1 0.1 0.8 0.8"},
+	{"0.1 0.1 0.8 0.8", "0 0 1 1"},
+	{"0 0.075 0.85 0.85", "0.15 0.075 0.85 0.85"},
+	{"0.15 0.075 0.85 0.85", "0 0.075 0.85 0.85"},
+}
+
+/* Function to compare filenames the way people count, so "2.jpg" comes before "10.jpg"
+ *
+ * Parameters:
+ *		a, b - the filenames
+ * Returns:
+ *		whether a comes before b
+ */
+func naturalLess(a string, b string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	for a != "" && b != "" {
+		aDigits, bDigits := leadingDigits(a), leadingDigits(b)
+		if aDigits > 0 && bDigits > 0 {
+			// Numbers are compared by value, ignoring leading zeros
+			aNumber, bNumber := strings.TrimLeft(a[:aDigits], "0"), strings.TrimLeft(b[:bDigits], "0")
+			if len(aNumber) != len(bNumber) {
+				return len(aNumber) < len(bNumber)
+			}
+			if aNumber != bNumber {
+				return aNumber < bNumber
+			}
+			a, b = a[aDigits:], b[bDigits:]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+/* Function to count the digits at the start of a string
+ *
+ * Parameters:
+ *		s - the string
+ * Returns:
+ *		the number of leading ASCII digits
+ */
+func leadingDigits(s string) int {
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	return n
+}
+
+/* Function to check the extension of a file
+ *
+ * Parameters:
+ *		name - the filename
+ *		extensions - the extensions to look for, in lower case
+ * Returns:
+ *		whether the file has one of the extensions
+ */
+func hasExtension(name string, extensions []string) bool {
+	extension := strings.ToLower(filepath.Ext(name))
+	for _, e := range extensions {
+		if extension == e {
+			return true
+		}
+	}
+	return false
+}
+
+/* Function to write a .slideshow with a slide per image
+ *
+ * Parameters:
+ *		title - the title of the story
+ *		images - the image filenames, relative to the .slideshow
+ *		audio - the narration filename relative to the .slideshow, empty if none
+ *		durations - the <timing duration> of each slide in milliseconds
+ * Returns:
+ *		the .slideshow XML
+ */
+func slideshowXML(title string, images []string, audio string, durations []string) []byte {
+	out := &bytes.Buffer{}
+	out.WriteString("<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<slideshow>\n")
+	fmt.Fprintf(out, "  <title>%s</title>\n", textEscaper.Replace(title))
+	for i, image := range images {
+		out.WriteString("  <slide>\n")
+		if audio != "" {
+			fmt.Fprintf(out, "    <audio>\n      <filename>%s</filename>\n    </audio>\n", textEscaper.Replace(audio))
+		}
+		fmt.Fprintf(out, "    <image>%s</image>\n", textEscaper.Replace(image))
+		motion := defaultMotions[i%len(defaultMotions)]
+		fmt.Fprintf(out, "    <motion start=\"%s\" end=\"%s\"/>\n", motion[0], motion[1])
+		fmt.Fprintf(out, "    <timing duration=\"%s\"/>\n", durations[i])
+		if i < len(images)-1 {
+			out.WriteString("    <transition duration=\"1000\">fade</transition>\n")
+		}
+		out.WriteString("  </slide>\n")
+	}
+	out.WriteString("</slideshow>\n")
+	return out.Bytes()
+}
+
+/* Function to create a .slideshow from a folder of images, to prototype a story. The images are
+ * shown in natural filename order with a crossfade and a Ken Burns motion each. With a narration
+ * audio, its length is split evenly between the slides.
+ *
+ * Parameters:
+ *		directory - the folder with the images
+ *		audioPath - path to the narration audio, empty to use the only audio in the folder if there is one
+ *		duration - length in milliseconds of each slide when there is no audio
+ *		outputPath - path of the .slideshow to write, it must not exist yet
+ *		v - verbose flag to determine what feedback to print
+ * Returns:
+ *		err - error in the event of a failure, nil if successful
+ */
+func Init(directory string, audioPath string, duration float64, outputPath string, v bool) error {
+	if _, err := os.Stat(outputPath); err == nil {
+		return fmt.Errorf("%s already exists", outputPath)
+	}
+
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return err
+	}
+	images := []string{}
+	audios := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if hasExtension(entry.Name(), imageExtensions) {
+			images = append(images, filepath.Join(directory, entry.Name()))
+		} else if hasExtension(entry.Name(), audioExtensions) {
+			audios = append(audios, filepath.Join(directory, entry.Name()))
+		}
+	}
+	if len(images) == 0 {
+		return fmt.Errorf("no images found in %s", directory)
+	}
+	sort.SliceStable(images, func(i, j int) bool { return naturalLess(filepath.Base(images[i]), filepath.Base(images[j])) })
+
+	if audioPath == "" && len(audios) == 1 {
+		audioPath = audios[0]
+		fmt.Printf("Using narration %s\n", audioPath)
+	}
+
+	// The slides share the narration evenly, or each last the given duration
+	total := duration / 1000 * float64(len(images))
+	if audioPath != "" {
+		total = FFmpeg.GetVideoLength(audioPath)
+	}
+	durations := []string{}
+	for i := range images {
+		durations = append(durations, durationMilliseconds(total*float64(i)/float64(len(images)), total*float64(i+1)/float64(len(images))))
+	}
+
+	outputDir := filepath.Dir(outputPath)
+	relative := func(file string) string {
+		rel, err := filepath.Rel(outputDir, file)
+		if err != nil {
+			return filepath.ToSlash(file)
+		}
+		return filepath.ToSlash(rel)
+	}
+	for i := range images {
+		images[i] = relative(images[i])
+		if v {
+			fmt.Printf("Slide %d: %s for %s ms\n", i+1, images[i], durations[i])
+		}
+	}
+	audio := ""
+	if audioPath != "" {
+		audio = relative(audioPath)
+	}
+
+	abs, err := filepath.Abs(directory)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		return err
+	}
+	fmt.Printf("Writing %s with %d slides...\n", outputPath, len(images))
+	return os.WriteFile(outputPath, slideshowXML(filepath.Base(abs), images, audio, durations), 0644)
+}
@@ -0,0 +1,285 @@
+package slideshow
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	FFmpeg "github.com/sillsdev/appbuilder-storybuilder/src/ffmpeg"
+	Timing "github.com/sillsdev/appbuilder-storybuilder/src/timing"
+)
+
+// Escaping of text and attribute values written by rewriteSlides
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+var attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+/* Structure of the elements added to a slide when instantiating a template
+ *	audio: filename of the narration, empty to leave the <audio> element as is
+ *	duration: the <timing duration> in milliseconds, replacing the one of the slide if it has one, empty to leave the <timing> element as is
+ *	start: the <filename start> of the narration in seconds, empty if the narration is played from its start
+ *	end: the <filename end> of the narration in seconds, empty if the narration is played to its end
+ */
+type slideChanges struct {
+	audio    string
+	duration string
+	start    string
+	end      string
+}
+
+/* Function to write a start tag
+ *
+ * Parameters:
+ *		out - buffer to write to
+ *		element - the start element
+ *		empty - whether to write it as an empty element tag
+ */
+func writeStartElement(out *bytes.Buffer, element xml.StartElement, empty bool) {
+	out.WriteString("<" + qualifiedName(element.Name))
+	for _, attr := range element.Attr {
+		fmt.Fprintf(out, ` %s="%s"`, qualifiedName(attr.Name), attrEscaper.Replace(attr.Value))
+	}
+	if empty {
+		out.WriteString("/")
+	}
+	out.WriteString(">")
+}
+
+/* Function to get the name of an element or attribute as written in the file
+ *
+ * Parameters:
+ *		name - the name read by RawToken
+ * Returns:
+ *		the name with its prefix, if any
+ */
+func qualifiedName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+/* Function to set an attribute of an element
+ *
+ * Parameters:
+ *		attrs - the attributes of the element
+ *		name - the attribute to set
+ *		value - its value
+ * Returns:
+ *		the attributes with the value replaced, or added at the end if the element did not have it
+ */
+func withAttr(attrs []xml.Attr, name string, value string) []xml.Attr {
+	for i := range attrs {
+		if attrs[i].Name.Space == "" && attrs[i].Name.Local == name {
+			attrs[i].Value = value
+			return attrs
+		}
+	}
+	return append(attrs, xml.Attr{Name: xml.Name{Local: name}, Value: value})
+}
+
+/* Function to rewrite a .slideshow token by token, keeping its formatting, comments and the elements
+ * StoryBuilder does not know, while adding <audio> and <timing> elements to slides or changing their timing
+ *
+ * Parameters:
+ *		data - the .slideshow XML
+ *		changes - the elements to add to each slide
+ * Returns:
+ *		the rewritten XML and an error if the XML is invalid
+ */
+func rewriteSlides(data []byte, changes []slideChanges) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	out := &bytes.Buffer{}
+	var pending *xml.StartElement
+	slide := -1
+	depth := 0
+	timed := false
+
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if end, ok := token.(xml.EndElement); ok && pending != nil && pending.Name == end.Name {
+			writeStartElement(out, *pending, true)
+			pending = nil
+			depth--
+			continue
+		}
+		if pending != nil {
+			writeStartElement(out, *pending, false)
+			pending = nil
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 2 && t.Name.Local == "slide" {
+				slide++
+				timed = false
+				writeStartElement(out, t, false)
+				if slide < len(changes) && changes[slide].audio != "" {
+					file := xml.StartElement{Name: xml.Name{Local: "filename"}}
+					if changes[slide].start != "" {
+						file.Attr = withAttr(file.Attr, "start", changes[slide].start)
+					}
+					if changes[slide].end != "" {
+						file.Attr = withAttr(file.Attr, "end", changes[slide].end)
+					}
+					out.WriteString("\n    <audio>\n      ")
+					writeStartElement(out, file, false)
+					fmt.Fprintf(out, "%s</filename>\n    </audio>", textEscaper.Replace(changes[slide].audio))
+				}
+				continue
+			}
+			element := t.Copy()
+			if depth == 3 && element.Name.Local == "timing" && slide >= 0 && slide < len(changes) && changes[slide].duration != "" {
+				element.Attr = withAttr(element.Attr, "duration", changes[slide].duration)
+				timed = true
+			}
+			pending = &element
+		case xml.EndElement:
+			if depth == 2 && t.Name.Local == "slide" && slide < len(changes) && changes[slide].duration != "" && !timed {
+				fmt.Fprintf(out, "  <timing duration=\"%s\"/>\n  ", changes[slide].duration)
+			}
+			depth--
+			fmt.Fprintf(out, "</%s>", qualifiedName(t.Name))
+		case xml.CharData:
+			out.WriteString(textEscaper.Replace(string(t)))
+		case xml.Comment:
+			fmt.Fprintf(out, "<!--%s-->", t)
+		case xml.ProcInst:
+			fmt.Fprintf(out, "<?%s %s?>", t.Target, t.Inst)
+		case xml.Directive:
+			fmt.Fprintf(out, "<!%s>", t)
+		}
+	}
+	return out.Bytes(), nil
+}
+
+/* Function to instantiate a template for a language: the narrated slides, the ones without a <timing>,
+ * get the narration audio and a timing computed from verse timestamps, and the result is written as
+ * a complete .slideshow
+ *
+ * Parameters:
+ *		templatePath - path to the template .slideshow
+ *		narrationPath - path to the narration audio
+ *		labelsPath - path to the verse timestamps, an Audacity label track or SAB timing file
+ *		outputPath - path of the .slideshow to write
+ *		v - verbose flag to determine what feedback to print
+ * Returns:
+ *		err - error in the event of a failure, nil if successful
+ */
+func Instantiate(templatePath string, narrationPath string, labelsPath string, outputPath string, v bool) error {
+	data, err := os.ReadFile(templatePath)
+	if err != nil {
+		return err
+	}
+	template := readSlideshowXML(templatePath)
+
+	narrated := []int{}
+	slides := []Timing.Slide{}
+	for i, slide := range template.Slide {
+		if slide.Timing.Duration == "" {
+			narrated = append(narrated, i)
+			slides = append(slides, Timing.Slide{Start: slide.Narration.Start, End: slide.Narration.End})
+		}
+	}
+
+	labels, err := Timing.ReadLabels(labelsPath)
+	if err != nil {
+		return err
+	}
+	audioDuration := FFmpeg.GetVideoLength(narrationPath)
+	times, err := Timing.SlideTimes(slides, labels, audioDuration)
+	if err != nil {
+		return err
+	}
+
+	return fillTemplate(data, template, narrated, times, narrationPath, audioDuration, outputPath, v)
+}
+
+/* Function to write a template with the narration audio and timings of its narrated slides
+ *
+ * Parameters:
+ *		data - the template .slideshow XML
+ *		template - the parsed template
+ *		narrated - indexes of the narrated slides
+ *		times - start time in seconds of each narrated slide in the narration followed by the end of the last one
+ *		narrationPath - path to the narration audio
+ *		audioDuration - length of the narration in seconds
+ *		outputPath - path of the .slideshow to write
+ *		v - verbose flag to determine what feedback to print
+ * Returns:
+ *		err - error in the event of a failure, nil if successful
+ */
+func fillTemplate(data []byte, template *slideshow_template, narrated []int, times []float64, narrationPath string, audioDuration float64, outputPath string, v bool) error {
+	audio, err := filepath.Rel(filepath.Dir(outputPath), narrationPath)
+	if err != nil {
+		audio = narrationPath
+	}
+	audio = filepath.ToSlash(audio)
+
+	// The lead-in before the first verse and anything after the last one are cut off the narration
+	start, end := "", ""
+	if times[0] > 0 {
+		start = formatSeconds(times[0])
+	}
+	if last := times[len(times)-1]; last < audioDuration-0.0005 {
+		end = formatSeconds(last)
+	}
+
+	changes := make([]slideChanges, len(template.Slide))
+	for n, i := range narrated {
+		changes[i] = slideChanges{duration: durationMilliseconds(times[n], times[n+1])}
+		if template.Slide[i].Audio.Filename.Name == "" {
+			changes[i].audio, changes[i].start, changes[i].end = audio, start, end
+		}
+		if v {
+			fmt.Printf("Slide %d (%s) starts at %.2f seconds and lasts %s ms\n", i+1, template.Slide[i].Narration.Start, times[n], changes[i].duration)
+		}
+	}
+
+	instantiated, err := rewriteSlides(data, changes)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(outputPath), os.ModePerm); err != nil {
+		return err
+	}
+	fmt.Printf("Writing %s...\n", outputPath)
+	return os.WriteFile(outputPath, instantiated, 0644)
+}
+
+/* Function to get the <timing duration> of a slide
+ *
+ * Parameters:
+ *		start - time in seconds the slide starts in the narration
+ *		end - time in seconds it ends
+ * Returns:
+ *		the duration in milliseconds
+ */
+func durationMilliseconds(start float64, end float64) string {
+	// Rounding the boundaries rather than the durations keeps the slides from drifting off the narration
+	return fmt.Sprintf("%.0f", math.Round(end*1000)-math.Round(start*1000))
+}
+
+/* Function to format a time for a <filename start> or <filename end> attribute
+ *
+ * Parameters:
+ *		seconds - the time in seconds
+ * Returns:
+ *		the time in seconds rounded to the millisecond
+ */
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(math.Round(seconds*1000)/1000, 'f', -1, 64)
+}
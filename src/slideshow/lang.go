@@ -0,0 +1,86 @@
+package slideshow
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+/* Function to choose the image of a slide for a language. The fallback chain is the image
+ * tagged with the language, then the untagged image, otherwise an error. Images that are
+ * .odg documents are skipped since they are only sources for converted images.
+ *
+ * Parameters:
+ *		images - the <image> elements of the slide
+ *		lang - the language to choose, empty to use the untagged image
+ * Returns:
+ *		the chosen image filename and an error if no image can be used
+ */
+func selectImage(images []image, lang string) (string, error) {
+	candidates := []image{}
+	for _, img := range images {
+		if strings.EqualFold(path.Ext(strings.TrimSpace(img.Name)), ".odg") {
+			continue
+		}
+		candidates = append(candidates, img)
+	}
+
+	if lang != "" {
+		for _, img := range candidates {
+			if strings.EqualFold(img.Lang, lang) {
+				return strings.TrimSpace(img.Name), nil
+			}
+		}
+	}
+	for _, img := range candidates {
+		if img.Lang == "" {
+			return strings.TrimSpace(img.Name), nil
+		}
+	}
+
+	return "", fmt.Errorf("no image for language %q or untagged image found", lang)
+}
+
+/* Function to choose the title of the slideshow for a language. The fallback chain is the
+ * title tagged with the language, then the untagged title, otherwise an error. When no
+ * language is requested the first title is used if there is no untagged title.
+ *
+ * Parameters:
+ *		titles - the <title> elements of the slideshow
+ *		lang - the language to choose
+ * Returns:
+ *		the chosen title ("" if the slideshow has none) and an error if no title matches
+ */
+func selectTitle(titles []title, lang string) (string, error) {
+	if len(titles) == 0 {
+		return "", nil
+	}
+
+	if lang != "" {
+		for _, t := range titles {
+			if strings.EqualFold(t.Lang, lang) {
+				return strings.TrimSpace(t.Name), nil
+			}
+		}
+	}
+	for _, t := range titles {
+		if t.Lang == "" {
+			return strings.TrimSpace(t.Name), nil
+		}
+	}
+	if lang == "" {
+		return strings.TrimSpace(titles[0].Name), nil
+	}
+
+	return "", fmt.Errorf("no title for language %q or untagged title found", lang)
+}
+
+// Function to get the title chosen for the slideshow's language
+func (s slideshow) Title() string {
+	return s.title
+}
+
+// Function to get the language the slideshow was parsed for
+func (s slideshow) Lang() string {
+	return s.lang
+}
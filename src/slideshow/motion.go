@@ -0,0 +1,44 @@
+package slideshow
+
+import (
+	"fmt"
+
+	"github.com/sillsdev/appbuilder-storybuilder/src/helper"
+	Motion "github.com/sillsdev/appbuilder-storybuilder/src/motion"
+)
+
+/* Function to give a Ken Burns motion to the narrated slides without a <motion>, so they do not stay still
+ *
+ * Parameters:
+ *		strategy - the motion strategy (see Motion.Generate)
+ *		seed - seed of the "random" strategy, each slide uses the seed plus its index so renders are reproducible
+ *		saliency - whether to centre the motions on the detailed areas of the images rather than on their brightness
+ *		v - verbose flag to determine what feedback to print
+ * Returns:
+ *		err - error if an image cannot be read or the strategy is unknown, nil if successful
+ */
+func (s slideshow) GenerateMotions(strategy string, seed int64, saliency bool, v bool) error {
+	for i := range s.images {
+		if !s.autoMotions[i] {
+			continue
+		}
+		// Zooms are centred on the image unless the saliency is used, pans always move toward the focus
+		focus := Motion.Point{X: 0.5, Y: 0.5}
+		if saliency || strategy == "pan" || strategy == "random" {
+			img, err := helper.ReadImage(s.images[i])
+			if err != nil {
+				return fmt.Errorf("slide %d: %w", i+1, err)
+			}
+			focus = Motion.Focus(img, saliency)
+		}
+		motions, err := Motion.Generate(strategy, focus, seed+int64(i))
+		if err != nil {
+			return err
+		}
+		if v {
+			fmt.Printf("Slide %d gets the motion %v to %v\n", i+1, motions[0], motions[1])
+		}
+		s.motions[i] = motions
+	}
+	return nil
+}
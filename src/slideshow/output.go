@@ -0,0 +1,169 @@
+package slideshow
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Default output filename template, the .slideshow filename with an .mp4 extension
+const defaultOutputTemplate = "{template}.mp4"
+
+// Metadata keys written as standard MP4 (iTunes style) atoms, any other key needs custom metadata tags
+var standardMetadataKeys = map[string]bool{
+	"title": true, "artist": true, "album_artist": true, "album": true, "composer": true, "date": true,
+	"comment": true, "genre": true, "copyright": true, "description": true, "synopsis": true,
+}
+
+// ISO 639-2 codes for the languages used by the SAB templates, MP4 stream languages must use three letters
+var iso639_2 = map[string]string{
+	"en": "eng", "fr": "fra", "nl": "nld", "pt": "por", "es": "spa", "de": "deu", "id": "ind",
+	"ru": "rus", "ar": "ara", "zh": "zho", "hi": "hin", "sw": "swa", "tpi": "tpi",
+}
+
+/* Function to get the values that can be used in output filename templates and metadata
+ *
+ * Parameters:
+ *		lowQuality - whether the video is rendered at the lower resolution
+ * Returns:
+ *		map of field name to value
+ */
+func (s slideshow) outputFields(lowQuality bool) map[string]string {
+	resolution := "720p"
+	if lowQuality {
+		resolution = "480p"
+	}
+
+	return map[string]string{
+		"title":      s.title,
+		"lang":       s.lang,
+		"story":      path.Base(strings.TrimSuffix(s.templateDirectory, "/")),
+		"template":   strings.TrimSuffix(s.templateName, ".slideshow"),
+		"date":       time.Now().Format("2006-01-02"),
+		"resolution": resolution,
+	}
+}
+
+/* Function to replace the {field} placeholders of a template
+ *
+ * Parameters:
+ *		template - the template containing {field} placeholders
+ *		fields - the values to substitute
+ *		sanitize - whether to remove characters that cannot be used in filenames from the values
+ * Returns:
+ *		the expanded string
+ */
+func expandFields(template string, fields map[string]string, sanitize bool) string {
+	replacements := []string{}
+	for name, value := range fields {
+		if sanitize {
+			value = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(`/\:*?"<>|`, r) {
+					return '_'
+				}
+				return r
+			}, value)
+		}
+		replacements = append(replacements, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(replacements...).Replace(template)
+}
+
+/* Function to create the filename of the final video from a template like "{lang}/{title}-{resolution}.mp4".
+ * Available fields are {title}, {lang}, {story} (folder of the .slideshow), {template} (.slideshow filename),
+ * {date} and {resolution}.
+ *
+ * Parameters:
+ *		nameTemplate - the output filename template, defaultOutputTemplate if empty
+ *		lowQuality - whether the video is rendered at the lower resolution
+ * Returns:
+ *		the filename relative to the output directory
+ *		err - error if the template is an absolute path or expands to an empty name
+ */
+func (s slideshow) OutputName(nameTemplate string, lowQuality bool) (string, error) {
+	if nameTemplate == "" {
+		nameTemplate = defaultOutputTemplate
+	}
+	if path.IsAbs(nameTemplate) || filepath.IsAbs(nameTemplate) {
+		return "", fmt.Errorf("output name %q must be relative to the output directory", nameTemplate)
+	}
+
+	// Empty fields (e.g. {lang} without -lang) leave empty folders, which are dropped so the
+	// name cannot start with a separator and end up in the filesystem root
+	segments := []string{}
+	for _, segment := range strings.Split(expandFields(nameTemplate, s.outputFields(lowQuality), true), "/") {
+		if segment != "" && segment != "." {
+			segments = append(segments, segment)
+		}
+	}
+	name := path.Clean(strings.Join(segments, "/"))
+	if name == "." || path.IsAbs(name) || filepath.IsAbs(name) {
+		return "", fmt.Errorf("output name %q expands to %q, expected a relative filename", nameTemplate, name)
+	}
+	if path.Ext(name) != ".mp4" {
+		name += ".mp4"
+	}
+	return name, nil
+}
+
+/* Function to create the ffmpeg arguments that tag the final video with metadata. The title and
+ * language come from the slideshow, other tags (artist, publisher, copyright, comment, ...) come from a
+ * JSON config file of key/value pairs whose values can use the same {field} placeholders as OutputName.
+ *
+ * Parameters:
+ *		configPath - path to the JSON metadata config file, ignored if empty
+ *		lowQuality - whether the video is rendered at the lower resolution
+ * Returns:
+ *		the ffmpeg metadata arguments and an error if the config file cannot be read
+ */
+func (s slideshow) Metadata(configPath string, lowQuality bool) ([]string, error) {
+	fields := s.outputFields(lowQuality)
+	tags := map[string]string{}
+	if s.title != "" {
+		tags["title"] = s.title
+	}
+
+	if configPath != "" {
+		data, err := os.ReadFile(configPath)
+		if err != nil {
+			return nil, err
+		}
+		config := map[string]string{}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("%s: %w", configPath, err)
+		}
+		for key, value := range config {
+			tags[strings.ToLower(key)] = expandFields(value, fields, false)
+		}
+	}
+
+	keys := []string{}
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	args := []string{}
+	custom := false
+	for _, key := range keys {
+		args = append(args, "-metadata", key+"="+tags[key])
+		custom = custom || !standardMetadataKeys[key]
+	}
+	if s.lang != "" {
+		lang := s.lang
+		if code, ok := iso639_2[strings.ToLower(lang)]; ok {
+			lang = code
+		}
+		args = append(args, "-metadata:s:a:0", "language="+lang)
+	}
+	if custom {
+		args = append(args, "-movflags", "+use_metadata_tags")
+	}
+
+	return args, nil
+}
@@ -0,0 +1,99 @@
+package slideshow
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	FFmpeg "github.com/sillsdev/appbuilder-storybuilder/src/ffmpeg"
+)
+
+/* Function to choose the slides of a preview
+ *
+ * Parameters:
+ *		spec - a number of seconds from the start (e.g. "10"), or "slides:" followed by slide numbers and ranges (e.g. "slides:1,3-5")
+ *		timings - duration of each slide in milliseconds
+ * Returns:
+ *		slides - indexes of the chosen slides in order
+ *		seconds - maximum length of the preview, 0 for no limit
+ *		err - error if the spec is invalid
+ */
+func previewSlides(spec string, timings []string) ([]int, float64, error) {
+	slides := []int{}
+
+	if strings.HasPrefix(spec, "slides:") {
+		for _, part := range strings.Split(strings.TrimPrefix(spec, "slides:"), ",") {
+			bounds := strings.SplitN(strings.TrimSpace(part), "-", 2)
+			first, err := strconv.Atoi(bounds[0])
+			last := first
+			if err == nil && len(bounds) == 2 {
+				last, err = strconv.Atoi(bounds[1])
+			}
+			if err != nil || first < 1 || last > len(timings) || first > last {
+				return nil, 0, fmt.Errorf("invalid preview slides %q, expected numbers from 1 to %d", part, len(timings))
+			}
+			for slide := first; slide <= last; slide++ {
+				slides = append(slides, slide-1)
+			}
+		}
+		return slides, 0, nil
+	}
+
+	seconds, err := strconv.ParseFloat(spec, 64)
+	if err != nil || seconds <= 0 {
+		return nil, 0, fmt.Errorf("invalid preview %q, expected seconds or slides:N,M-O", spec)
+	}
+
+	// Only the slides needed to fill the preview are rendered
+	total := 0.0
+	for i := 0; i < len(timings) && total < seconds; i++ {
+		duration, err := strconv.ParseFloat(strings.TrimSpace(timings[i]), 64)
+		if err != nil {
+			duration = 5000
+		}
+		total += duration / 1000
+		slides = append(slides, i)
+	}
+	return slides, seconds, nil
+}
+
+/* Function to create a short teaser of the slideshow as MP4, GIF and WebP instead of the full video
+ *
+ * Parameters:
+ *		spec - a number of seconds from the start, or "slides:" followed by slide numbers and ranges
+ *		renderer - renderer of the zoom/pan effects, "ffmpeg" or "go"
+ *		tempDirectory - filepath to the temp folder to store the temporary videos created
+ *		outputBase - path and filename without extension to save the previews to
+ *		v - verbose flag to determine what feedback to print
+ * Returns:
+ *		err - error if the spec is invalid
+ */
+func (s slideshow) CreatePreview(spec string, renderer string, tempDirectory string, outputBase string, v bool) error {
+	slides, seconds, err := previewSlides(spec, s.timings)
+	if err != nil {
+		return err
+	}
+
+	images := []string{}
+	timings := []string{}
+	audios := []string{}
+	motions := [][][]float64{}
+	easings := []string{}
+	fits := []string{}
+	for _, i := range slides {
+		images = append(images, s.images[i])
+		timings = append(timings, s.timings[i])
+		audios = append(audios, s.audios[i])
+		motions = append(motions, s.motions[i])
+		easings = append(easings, s.easings[i])
+		fits = append(fits, s.fits[i])
+	}
+	if v {
+		fmt.Printf("Preview uses slides %v\n", slides)
+	}
+
+	FFmpeg.MakeTempVideosWithoutAudio(images, timings, audios, motions, easings, fits, renderer, tempDirectory, v)
+	FFmpeg.MakePreview(len(images), seconds, tempDirectory, outputBase)
+
+	return nil
+}
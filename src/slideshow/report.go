@@ -0,0 +1,64 @@
+package slideshow
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	FFmpeg "github.com/sillsdev/appbuilder-storybuilder/src/ffmpeg"
+)
+
+/* Structure of the JSON report written after a render
+ *	Template: the .slideshow filename
+ *	Output: path of the final video
+ *	Title: title of the story
+ *	Lang: language of the slideshow
+ *	Duration: length of the video in seconds
+ *	RenderSeconds: time taken by the render
+ *	Slides: start and end time of each slide in the video
+ *	Loudness: values measured by loudness normalization, if it was done
+ */
+type renderReport struct {
+	Template      string                      `json:"template"`
+	Output        string                      `json:"output"`
+	Title         string                      `json:"title,omitempty"`
+	Lang          string                      `json:"lang,omitempty"`
+	Duration      float64                     `json:"duration"`
+	RenderSeconds float64                     `json:"renderSeconds"`
+	Slides        []timelineSlide             `json:"slides"`
+	Loudness      *FFmpeg.LoudnessMeasurement `json:"loudness,omitempty"`
+}
+
+/* Function to create the report of a render
+ *
+ * Parameters:
+ *		output - path of the final video
+ *		offsets - start time of each slide in seconds followed by the total length (see CreateVideo)
+ *		loudness - measured loudness of the mix, nil if it was not normalized
+ *		renderSeconds - time taken by the render
+ * Returns:
+ *		the report
+ */
+func (s slideshow) report(output string, offsets []float64, loudness *FFmpeg.LoudnessMeasurement, renderSeconds float64) renderReport {
+	return renderReport{s.templateName, output, s.title, s.lang, offsets[len(offsets)-1], renderSeconds, s.timelineSlides(offsets), loudness}
+}
+
+/* Function to write the JSON report of a render
+ *
+ * Parameters:
+ *		reportPath - path of the JSON file
+ *		output - path of the final video
+ *		offsets - start time of each slide in seconds followed by the total length (see CreateVideo)
+ *		loudness - measured loudness of the mix, nil if it was not normalized
+ *		renderSeconds - time taken by the render
+ * Returns:
+ *		err - error in the event of a failure, nil if successful
+ */
+func (s slideshow) WriteReport(reportPath string, output string, offsets []float64, loudness *FFmpeg.LoudnessMeasurement, renderSeconds float64) error {
+	data, err := json.MarshalIndent(s.report(output, offsets, loudness, renderSeconds), "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Writing render report to %s...\n", reportPath)
+	return os.WriteFile(reportPath, data, 0644)
+}
@@ -0,0 +1,98 @@
+package slideshow
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	Timing "github.com/sillsdev/appbuilder-storybuilder/src/timing"
+)
+
+/* Function to compare the slides of a .slideshow with the slides recorded in a SAB log
+ *
+ * Parameters:
+ *		slides - the slides of the .slideshow
+ *		logSlides - the slides of the log
+ * Returns:
+ *		a description of each discrepancy, empty if the .slideshow matches the log
+ */
+func compareTimings(slides []slide, logSlides []Timing.LogSlide) []string {
+	discrepancies := []string{}
+	if len(slides) != len(logSlides) {
+		discrepancies = append(discrepancies, fmt.Sprintf("the slideshow has %d slides, the log has %d", len(slides), len(logSlides)))
+	}
+
+	for i := 0; i < len(slides) && i < len(logSlides); i++ {
+		logged := logSlides[i]
+		expected := fmt.Sprintf("%.0f", math.Round(logged.Duration*1000))
+		if duration := slides[i].Timing.Duration; duration != expected {
+			discrepancies = append(discrepancies, fmt.Sprintf("slide %d: <timing duration> is %q, the log has %s ms", i+1, duration, expected))
+		}
+		if start := slides[i].Narration.Start; start != logged.StartRef {
+			discrepancies = append(discrepancies, fmt.Sprintf("slide %d: <narration start> is %q, the log has %q", i+1, start, logged.StartRef))
+		}
+		// The log only records the end reference of the last narrated slide
+		if end := slides[i].Narration.End; logged.EndRef != "" && end != logged.EndRef {
+			discrepancies = append(discrepancies, fmt.Sprintf("slide %d: <narration end> is %q, the log has %q", i+1, end, logged.EndRef))
+		}
+	}
+	return discrepancies
+}
+
+/* Function to audit a .slideshow produced by Scripture App Builder against its log (sab.log): the
+ * discrepancies between the timings and verse references of the .slideshow and of the log are
+ * reported, or the .slideshow is rebuilt with the timings of the log
+ *
+ * Parameters:
+ *		slideshowPath - path to the .slideshow
+ *		logPath - path to the SAB log
+ *		outputPath - path of the .slideshow to write with the timings of the log, empty to only verify
+ *		v - verbose flag to determine what feedback to print
+ * Returns:
+ *		err - error if the log cannot be read or, when verifying, if there are discrepancies
+ */
+func AuditSABLog(slideshowPath string, logPath string, outputPath string, v bool) error {
+	logSlides, err := Timing.ReadSABLog(logPath)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(slideshowPath)
+	if err != nil {
+		return err
+	}
+	template := readSlideshowXML(slideshowPath)
+
+	discrepancies := compareTimings(template.Slide, logSlides)
+	for _, discrepancy := range discrepancies {
+		fmt.Println(discrepancy)
+	}
+
+	if outputPath == "" {
+		if len(discrepancies) > 0 {
+			return fmt.Errorf("%d discrepancies between %s and %s", len(discrepancies), slideshowPath, logPath)
+		}
+		fmt.Printf("%s matches %s\n", slideshowPath, logPath)
+		return nil
+	}
+
+	if len(template.Slide) != len(logSlides) {
+		return fmt.Errorf("cannot rebuild the timings, the slideshow and the log have different slides")
+	}
+	changes := make([]slideChanges, len(logSlides))
+	for i, logged := range logSlides {
+		changes[i].duration = fmt.Sprintf("%.0f", math.Round(logged.Duration*1000))
+		if v {
+			fmt.Printf("Slide %d lasts %s ms\n", i+1, changes[i].duration)
+		}
+	}
+	rebuilt, err := rewriteSlides(data, changes)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(outputPath), os.ModePerm); err != nil {
+		return err
+	}
+	fmt.Printf("Writing %s...\n", outputPath)
+	return os.WriteFile(outputPath, rebuilt, 0644)
+}
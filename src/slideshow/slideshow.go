@@ -27,6 +27,17 @@ import (
  *	timings: strings describing the time (in milliseconds) for each slide to last, also used for motions
  *	motions: arrays of floats describing the dimensions and positions for the start and end rectangles for zoom/pan effects
  *	templateName: string parsed from the .slideshow filename to be used for the final video product
+ *	title: the title of the story chosen for the language, used for generated title cards and output naming
+ *	lang: the language chosen for the multilingual elements, empty for untagged elements
+ *	templateDirectory: folder path leading up to the .slideshow file
+ *	narrations: verse reference (e.g. JHN.1.1) where the narration of each slide starts, empty if not specified
+ *	chapterLabels: custom chapter title of each slide from the <chapter> element, empty if not specified
+ *	mixes: background music of each slide, mixed under its narration by MixMusic, empty if none
+ *	extracts: narration segments to cut out of longer audios by ExtractNarration
+ *	autoMotions: whether each slide gets a motion from GenerateMotions, true for the narrated slides without a <motion>
+ *	easings: easing curve of the motion of each slide (linear, ease-in-out, cubic or sine)
+ *	fits: how the motion rectangles of each slide are fitted to the 16:9 frame (fit or fill)
+ *	transitionSplits: where each transition is played around the start of the next slide (centre, end or start)
  */
 type slideshow struct {
 	images              []string
@@ -37,6 +48,26 @@ type slideshow struct {
 	motions             [][][]float64
 	templateName        string
 	tempPath            string
+	title               string
+	lang                string
+	templateDirectory   string
+	narrations          []string
+	chapterLabels       []string
+	mixes               []musicMix
+	extracts            []audioExtract
+	autoMotions         []bool
+	easings             []string
+	fits                []string
+	transitionSplits    []string
+}
+
+/* Structure of the background music of a slide
+ *	music: filepath to the background music
+ *	narration: filepath to the narration audio to mix the music under, empty if the slide only has music
+ */
+type musicMix struct {
+	music     string
+	narration string
 }
 
 /* Function to create a new slideshow from a .slideshow template. The code parses the pieces out
@@ -44,11 +75,12 @@ type slideshow struct {
  *
  * Parameters:
  *			slideshowDirectory - the filepath to the .slideshow to be parsed
+ *			lang - the language to choose the titles and images for, empty for untagged elements
  *			v - verbose flag to determine what feedback to print
  * Returns:
  *			slideshow - the filled slideshow structure, containing all the data parsed
  */
-func NewSlideshow(slideshowDirectory string, v bool, tempPath string) slideshow {
+func NewSlideshow(slideshowDirectory string, lang string, v bool, tempPath string) slideshow {
 	slideshow_template := readSlideshowXML(slideshowDirectory)
 
 	Images := []string{}
@@ -57,38 +89,87 @@ func NewSlideshow(slideshowDirectory string, v bool, tempPath string) slideshow
 	TransitionDurations := []string{}
 	Timings := []string{}
 	Motions := [][][]float64{}
+	Narrations := []string{}
+	ChapterLabels := []string{}
+	Mixes := []musicMix{}
+	Extracts := []audioExtract{}
+	AutoMotions := []bool{}
+	Easings := []string{}
+	Fits := []string{}
+	TransitionSplits := []string{}
 
 	fmt.Println("Parsing .slideshow file...")
 
 	templateDir, template_name := splitFileNameFromDirectory(slideshowDirectory)
 
+	// Named transitions are added to or replaced by the transitions.json next to the .slideshow
+	registryPath := path.Join(templateDir, "transitions.json")
+	if _, err := os.Stat(registryPath); err != nil {
+		registryPath = ""
+	}
+	transitions, err := FFmpeg.ReadTransitions(registryPath)
+	helper.Check(err)
+
 	for i, slide := range slideshow_template.Slide {
 		Timings = append(Timings, slide.Timing.Duration)
+		mix := musicMix{}
+		narration := ""
+		if slide.Audio.Filename.Name != "" {
+			narration = templateDir + slide.Audio.Filename.Name
+			if file := slide.Audio.Filename; file.Start != "" || file.End != "" {
+				// The narration is cut out of a longer audio by ExtractNarration
+				narration = addExtract(&Extracts, audioExtract{narration, file.Start, file.End, labelsPath(templateDir, file.Labels), ""}, tempPath)
+			}
+		}
+		music := ""
 		if slide.Audio.Background_Filename.Path != "" { // Intro music is stored differently in the xml
-			if slide.Audio.Filename.Name != "" {
-				FFmpeg.MergeAudios(templateDir+slide.Audio.Background_Filename.Path, templateDir+slide.Audio.Filename.Name, Timings[i-1], Timings[i], tempPath)
-				Audios = append(Audios, path.Join(tempPath, "mergedAudio.mp3"))
+			music = templateDir + slide.Audio.Background_Filename.Path
+		} else if slide.Audio.Background == "continue" && i > 0 { // The music of the previous slide keeps playing
+			music = Mixes[i-1].music
+		}
+		if music != "" {
+			if narration != "" {
+				// The music is mixed with the narration by MixMusic
+				mix = musicMix{music, narration}
+				Audios = append(Audios, path.Join(tempPath, fmt.Sprintf("mixed-%02d.wav", i+1)))
 			} else {
-				Audios = append(Audios, templateDir+slide.Audio.Background_Filename.Path)
+				mix = musicMix{music, ""}
+				Audios = append(Audios, music)
 			}
 		} else {
-			if slide.Audio.Filename.Name != "" {
-				Audios = append(Audios, templateDir+slide.Audio.Filename.Name)
-			} else {
-				Audios = append(Audios, "")
-			}
+			Audios = append(Audios, narration)
 		}
-		Images = append(Images, templateDir+slide.Image.Name)
-		if slide.Transition.Type == "" { // Default to a basic crossfade if no transition provided
+		image, err := selectImage(slide.Image, lang)
+		if err != nil {
+			helper.Check(fmt.Errorf("slide %d: %w", i+1, err))
+		}
+		Images = append(Images, templateDir+image)
+		transitionType := strings.TrimSpace(slide.Transition.Type)
+		if slide.Transition.TypeAttr != "" {
+			transitionType = slide.Transition.TypeAttr
+		}
+		if transitionType == "" { // Default to a basic crossfade if no transition provided
 			Transitions = append(Transitions, "fade")
 		} else {
-			Transitions = append(Transitions, slide.Transition.Type)
+			xfade, err := FFmpeg.XfadeTransition(transitions, transitionType)
+			if err != nil {
+				helper.Check(fmt.Errorf("slide %d: %w", i+1, err))
+			}
+			Transitions = append(Transitions, xfade)
 		}
 		if slide.Transition.Duration == "" { // Default to 1000ms transition if none provided
 			TransitionDurations = append(TransitionDurations, "1000")
 		} else {
 			TransitionDurations = append(TransitionDurations, slide.Transition.Duration)
 		}
+		split := "centre" // Split the transition between the two slides if not specified
+		if slide.Transition.Split != "" {
+			split = slide.Transition.Split
+			if err := FFmpeg.CheckSplit(split); err != nil {
+				helper.Check(fmt.Errorf("slide %d: %w", i+1, err))
+			}
+		}
+		TransitionSplits = append(TransitionSplits, split)
 		var motions = [][]float64{}
 		if slide.Motion.Start == "" { // If no motion specified, default to a static "zoom/pan" effect
 			motions = [][]float64{{0, 0, 1, 1}, {0, 0, 1, 1}}
@@ -96,13 +177,41 @@ func NewSlideshow(slideshowDirectory string, v bool, tempPath string) slideshow
 			motions = [][]float64{helper.ConvertStringToFloat(slide.Motion.Start), helper.ConvertStringToFloat(slide.Motion.End)}
 		}
 		Motions = append(Motions, motions)
+		easing := "linear"
+		if slide.Motion.Easing != "" {
+			easing = slide.Motion.Easing
+			if err := FFmpeg.CheckEasing(easing); err != nil {
+				helper.Check(fmt.Errorf("slide %d: %w", i+1, err))
+			}
+		}
+		Easings = append(Easings, easing)
+		fit := "fit"
+		if slide.Motion.Fit != "" {
+			fit = slide.Motion.Fit
+			if err := FFmpeg.CheckFit(fit); err != nil {
+				helper.Check(fmt.Errorf("slide %d: %w", i+1, err))
+			}
+		}
+		Fits = append(Fits, fit)
+		// Title and credits slides have no narration and are left static
+		AutoMotions = append(AutoMotions, slide.Motion.Start == "" && slide.Audio.Filename.Name != "")
+		Narrations = append(Narrations, slide.Narration.Start)
+		ChapterLabels = append(ChapterLabels, strings.TrimSpace(slide.Chapter))
+		Mixes = append(Mixes, mix)
 	}
 
+	title, err := selectTitle(slideshow_template.Title, lang)
+	helper.Check(err)
+
 	if v {
+		if lang != "" {
+			fmt.Printf("Using language %s, title: %s\n", lang, title)
+		}
 		fmt.Printf("Parsed %d images, %d audios, %d transitions, %d transition durations, %d timings, and %d motions, from %s\n",
 			len(Images), len(Audios), len(Transitions), len(TransitionDurations), len(Timings), len(Motions), template_name)
 	}
-	slideshow := slideshow{Images, Audios, Transitions, TransitionDurations, Timings, Motions, template_name, tempPath}
+	slideshow := slideshow{Images, Audios, Transitions, TransitionDurations, Timings, Motions, template_name, tempPath, title, lang, templateDir,
+		Narrations, ChapterLabels, Mixes, Extracts, AutoMotions, Easings, Fits, TransitionSplits}
 
 	fmt.Println("Parsing completed...")
 
@@ -123,21 +232,6 @@ func ternaryInt(condition bool, x int, y int) int {
 	return y
 }
 
-func readImage(name string) (Image.Image, error) {
-	fd, err := os.Open(name)
-	if err != nil {
-		return nil, err
-	}
-	defer fd.Close()
-
-	img, err := jpeg.Decode(fd)
-	if err != nil {
-		return nil, err
-	}
-
-	return img, nil
-}
-
 func percentToPixel(percent float64, whole int) int {
 	return int(math.Floor(percent*float64(whole) + 0.5))
 }
@@ -164,7 +258,7 @@ func enlargeBoundsToAspectRatio(input Image.Rectangle, targetRatio float64) Imag
 }
 
 func (s slideshow) CropImage(i int, v bool) (string, error) {
-	img, err := readImage(s.images[i])
+	img, err := helper.ReadImage(s.images[i])
 	if err != nil {
 		return "", err
 	}
@@ -307,9 +401,18 @@ func (s slideshow) ScaleImages(lowQuality bool, v bool) {
  *			useOldFade - specifies whether to use the old fade style instead of XFade, if desired
  *			tempDirectory - filepath to the temp folder to store the temporary videos created
  *			outputDirectory - filepath to the location to store the final completed video
+ *			outputName - filename of the final video relative to the output directory (see OutputName)
+ *			metadata - ffmpeg metadata arguments to tag the final video with (see Metadata)
+ *			chapterMode - "slide" or "verse" to write chapter markers to the final video, empty for no chapters
+ *			loudness - EBU R128 target to normalize the final mix to, nil to keep the mix as is
+ *			fades - fades of the background music and at the cuts and transitions of the audio
+ *			renderer - renderer of the zoom/pan effects, "ffmpeg" or "go"
  *			v - verbose flag to determine what feedback to print
+ * Returns:
+ *			the start time in seconds of each slide in the final video, followed by its total length
+ *			the measured loudness of the mix, nil if it was not normalized
  */
-func (s slideshow) CreateVideo(useOldfade bool, tempDirectory string, outputDirectory string, v bool) {
+func (s slideshow) CreateVideo(useOldfade bool, tempDirectory string, outputDirectory string, outputName string, metadata []string, chapterMode string, loudness *FFmpeg.LoudnessTarget, fades FFmpeg.AudioFades, renderer string, v bool) ([]float64, *FFmpeg.LoudnessMeasurement) {
 	if v {
 		fmt.Println("Temp Directory: " + tempDirectory)
 		fmt.Println("Output Directory: " + outputDirectory)
@@ -319,23 +422,30 @@ func (s slideshow) CreateVideo(useOldfade bool, tempDirectory string, outputDire
 	var fadeType string = FFmpeg.ParseVersion()
 	useXfade := fadeType == "X" && !useOldfade
 
-	final_template_name := strings.TrimSuffix(s.templateName, ".slideshow")
-
+	var offsets []float64
 	if useXfade {
 		fmt.Println("FFmpeg version is bigger than 4.3.0, using Xfade transition method...")
-		FFmpeg.MakeTempVideosWithoutAudio(s.images, s.timings, s.audios, s.motions, tempDirectory, v)
-		FFmpeg.MergeTempVideos(s.images, s.transitions, s.transitionDurations, s.timings, tempDirectory, v)
-		FFmpeg.AddAudio(s.timings, s.audios, tempDirectory, v)
-		FFmpeg.CopyFinal(tempDirectory, outputDirectory, final_template_name)
+		FFmpeg.MakeTempVideosWithoutAudio(s.images, s.timings, s.audios, s.motions, s.easings, s.fits, renderer, tempDirectory, v)
+		offsets = FFmpeg.MergeTempVideos(s.images, s.transitions, s.transitionDurations, s.transitionSplits, s.timings, tempDirectory, v)
 	} else {
 		fmt.Println("FFmpeg version is smaller than 4.3.0, using old fade transition method...")
-		FFmpeg.MakeTempVideosWithoutAudio(s.images, s.timings, s.audios, s.motions, tempDirectory, v)
-		FFmpeg.MergeTempVideosOldFade(s.images, s.transitionDurations, s.timings, tempDirectory, v)
-		FFmpeg.AddAudio(s.timings, s.audios, tempDirectory, v)
-		FFmpeg.CopyFinal(tempDirectory, outputDirectory, final_template_name)
+		FFmpeg.MakeTempVideosWithoutAudio(s.images, s.timings, s.audios, s.motions, s.easings, s.fits, renderer, tempDirectory, v)
+		offsets = FFmpeg.MergeTempVideosOldFade(s.images, s.transitionDurations, s.transitionSplits, s.timings, tempDirectory, v)
 	}
+	mix := FFmpeg.MixAudio(s.audioTimeline(fades), tempDirectory, v)
+
+	var measured *FFmpeg.LoudnessMeasurement
+	if loudness != nil {
+		measurement := FFmpeg.NormalizeLoudness(mix, *loudness, v)
+		measured = &measurement
+	}
+	FFmpeg.AddAudio(mix, tempDirectory, v)
+
+	FFmpeg.CopyFinal(tempDirectory, outputDirectory, outputName, metadata, s.writeChapters(offsets, chapterMode, tempDirectory))
 
 	fmt.Println("Finished making video...")
+
+	return offsets, measured
 }
 
 // Helper function to generate an overlaid video of the software's result and a comparison video
@@ -2,13 +2,24 @@ package slideshow
 
 import (
 	"fmt"
+	Image "image"
+	"image/png"
+	"math"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
+
+	FFmpeg "github.com/sillsdev/appbuilder-storybuilder/src/ffmpeg"
+	Timing "github.com/sillsdev/appbuilder-storybuilder/src/timing"
 )
 
 func TestReadSlideshow(t *testing.T) {
 	templateName := "../../TestInput/test.slideshow"
 
-	slideshow := NewSlideshow(templateName, false, "../../TestInput")
+	slideshow := NewSlideshow(templateName, "", false, "../../TestInput")
 
 	expectedImages := []string{"../../TestInput/Jn01.1-18-title.jpg", "../../TestInput/./VB-John 1v1.jpg", "../../TestInput/./VB-John 1v3.jpg", "../../TestInput/./VB-John 1v4.jpg", "../../TestInput/./VB-John 1v5a.jpg",
 		"../../TestInput/./VB-John 1v5b.jpg", "../../TestInput/./VB-John 1v6.jpg", "../../TestInput/Gospel of John-credits.jpg"}
@@ -32,6 +43,12 @@ func TestReadSlideshow(t *testing.T) {
 		}
 	}
 
+	for i, split := range slideshow.transitionSplits {
+		if split != "centre" {
+			t.Errorf("expected transition split %d to be centre, but got %s", i, split)
+		}
+	}
+
 	expectedTransitionDurations := []string{"1000", "1000", "2000", "1000", "1000", "3000", "3000"}
 	for i := 0; i < len(expectedTransitionDurations); i++ {
 		if expectedTransitionDurations[i] != slideshow.transitionDurations[i] {
@@ -77,3 +94,432 @@ func TestReadSlideshow(t *testing.T) {
 		}
 	}
 }
+
+func TestSelectLanguage(t *testing.T) {
+	images := []image{{"en", "title-eng.odg"}, {"fr", "title-fra.jpg"}, {"", "title.jpg"}}
+	titles := []title{{"en", "The Word"}, {"fr", "La Parole"}}
+
+	tests := []struct {
+		lang      string
+		wantImage string
+		wantTitle string
+		wantErr   bool
+	}{
+		{"fr", "title-fra.jpg", "La Parole", false},
+		{"en", "title.jpg", "The Word", false},
+		{"", "title.jpg", "The Word", false},
+		{"es", "title.jpg", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			gotImage, err := selectImage(images, tt.lang)
+			if err != nil || gotImage != tt.wantImage {
+				t.Errorf("selectImage(%q) = %v, %v, want %v", tt.lang, gotImage, err, tt.wantImage)
+			}
+			gotTitle, err := selectTitle(titles, tt.lang)
+			if (err != nil) != tt.wantErr || gotTitle != tt.wantTitle {
+				t.Errorf("selectTitle(%q) = %v, %v, want %v", tt.lang, gotTitle, err, tt.wantTitle)
+			}
+		})
+	}
+
+	if _, err := selectImage([]image{{"en", "credits-eng.odg"}}, "en"); err == nil {
+		t.Error("expected an error when only .odg images are available")
+	}
+}
+
+func TestOutputName(t *testing.T) {
+	s := slideshow{templateName: "eng Jn01.1-18.slideshow", title: "The Word: Part 1", lang: "en", templateDirectory: "../[eng] World English Bible/Jn01.01-18 The Word/"}
+
+	tests := []struct {
+		template   string
+		lowQuality bool
+		want       string
+	}{
+		{"", false, "eng Jn01.1-18.mp4"},
+		{"{lang}/{title}-{resolution}.mp4", false, "en/The Word_ Part 1-720p.mp4"},
+		{"{story}/{lang}-{resolution}", true, "Jn01.01-18 The Word/en-480p.mp4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			if got, err := s.OutputName(tt.template, tt.lowQuality); err != nil || got != tt.want {
+				t.Errorf("OutputName(%q) = %v, %v, want %v", tt.template, got, err, tt.want)
+			}
+		})
+	}
+
+	// Without a language, the empty {lang} folder is dropped instead of leaving a name in the filesystem root
+	s.lang = ""
+	if got, err := s.OutputName("{lang}/{title}-{resolution}.mp4", false); err != nil || got != "The Word_ Part 1-720p.mp4" {
+		t.Errorf("OutputName() without lang = %v, %v, want The Word_ Part 1-720p.mp4", got, err)
+	}
+	if got, err := s.OutputName("{lang}/{story}//{lang}-{resolution}", false); err != nil || got != "Jn01.01-18 The Word/-720p.mp4" {
+		t.Errorf("OutputName() without lang = %v, %v, want Jn01.01-18 The Word/-720p.mp4", got, err)
+	}
+	for _, template := range []string{"/videos/{title}.mp4", "{lang}"} {
+		if got, err := s.OutputName(template, false); err == nil {
+			t.Errorf("OutputName(%q) = %v, want an error", template, got)
+		}
+	}
+}
+
+func TestChapters(t *testing.T) {
+	s := NewSlideshow("../../TestInput/test.slideshow", "", false, "../../TestInput")
+	offsets := []float64{0, 5, 14.4, 20.36, 24.56, 26.84, 29.12, 40, 45}
+
+	tests := []struct {
+		mode       string
+		wantTitles []string
+		wantStarts []float64
+	}{
+		{"slide", []string{"The Word", "JHN 1:1", "JHN 1:3", "JHN 1:4", "JHN 1:5", "Slide 6", "JHN 1:6", "Credits"}, []float64{0, 5, 14.4, 20.36, 24.56, 26.84, 29.12, 40}},
+		{"verse", []string{"The Word", "JHN 1:1", "JHN 1:3", "JHN 1:4", "JHN 1:5", "JHN 1:6", "Credits"}, []float64{0, 5, 14.4, 20.36, 24.56, 29.12, 40}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			chapters := s.chapters(offsets, tt.mode)
+			if len(chapters) != len(tt.wantTitles) {
+				t.Fatalf("expected %d chapters, but got %d", len(tt.wantTitles), len(chapters))
+			}
+			for i, chapter := range chapters {
+				if chapter.Title != tt.wantTitles[i] || chapter.Start != tt.wantStarts[i] {
+					t.Errorf("expected chapter %d to be %s at %f, but got %s at %f", i, tt.wantTitles[i], tt.wantStarts[i], chapter.Title, chapter.Start)
+				}
+			}
+			if chapters[len(chapters)-1].End != 45 {
+				t.Errorf("expected the last chapter to end at 45, but got %f", chapters[len(chapters)-1].End)
+			}
+		})
+	}
+}
+
+func TestPosterTime(t *testing.T) {
+	offsets := []float64{0, 5, 14.4, 20.36}
+
+	tests := []struct {
+		spec    string
+		want    float64
+		wantErr bool
+	}{
+		{"slide:1", 2.5, false},
+		{"slide:2", 9.7, false},
+		{"12", 12, false},
+		{"slide:4", 0, true},
+		{"30", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := posterTime(tt.spec, offsets)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("posterTime(%q) = %v, %v, want %v", tt.spec, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviewSlides(t *testing.T) {
+	timings := []string{"5000", "9400", "5960", "4200"}
+
+	tests := []struct {
+		spec        string
+		wantSlides  []int
+		wantSeconds float64
+		wantErr     bool
+	}{
+		{"10", []int{0, 1}, 10, false},
+		{"slides:1,3-4", []int{0, 2, 3}, 0, false},
+		{"slides:5", nil, 0, true},
+		{"soon", nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			slides, seconds, err := previewSlides(tt.spec, timings)
+			if (err != nil) != tt.wantErr || seconds != tt.wantSeconds || fmt.Sprint(slides) != fmt.Sprint(tt.wantSlides) {
+				t.Errorf("previewSlides(%q) = %v, %v, %v, want %v, %v", tt.spec, slides, seconds, err, tt.wantSlides, tt.wantSeconds)
+			}
+		})
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	s := NewSlideshow("../../TestInput/test.slideshow", "", false, "../../TestInput")
+	got := s.timeline("test.mp3")
+
+	if len(got.Slides) != 8 || got.Duration < 44.99 || got.Duration > 45.01 {
+		t.Fatalf("expected 8 slides lasting 45 seconds, but got %d slides lasting %f", len(got.Slides), got.Duration)
+	}
+	second := got.Slides[1]
+	if second.Image != "./VB-John 1v1.jpg" || second.Start != 5 || second.End != 14.4 || second.Narration != "JHN.1.1" {
+		t.Errorf("unexpected second slide %+v", second)
+	}
+}
+
+func TestAudioFades(t *testing.T) {
+	s := NewSlideshow("../../TestInput/test.slideshow", "", false, "../../TestInput")
+	fades := s.audioFades(FFmpeg.AudioFades{MusicIn: 1, MusicOut: 2, Click: 0.01})
+
+	// The intro music fades in and out, the narration only gets anti-click fades
+	want := FFmpeg.Fade{In: 1, Out: 2}
+	if fades[0] != want {
+		t.Errorf("audioFades()[0] = %v, want %v", fades[0], want)
+	}
+	want = FFmpeg.Fade{In: 0.01, Out: 0.01}
+	if fades[1] != want {
+		t.Errorf("audioFades()[1] = %v, want %v", fades[1], want)
+	}
+}
+
+func TestAudioTimeline(t *testing.T) {
+	s := NewSlideshow("../../TestInput/test.slideshow", "", false, "../../TestInput")
+	clips := s.audioTimeline(FFmpeg.AudioFades{})
+
+	// The narration continues across slides 2 to 7, the credits slide is silent until the end of the video
+	if len(clips) != 8 {
+		t.Fatalf("audioTimeline() has %d clips, want 8", len(clips))
+	}
+	if clips[7].Source != "" || clips[7].Start != 40 || math.Abs(clips[7].Duration-5) > 1e-9 {
+		t.Errorf("audioTimeline()[7] = %v, want 5 seconds of silence at 40 seconds", clips[7])
+	}
+	clips = clips[:7]
+	wantSourceStarts := []float64{0, 0, 9.4, 15.36, 19.56, 21.84, 24.12}
+	wantStarts := []float64{0, 5, 14.4, 20.36, 24.56, 26.84, 29.12}
+	for i, clip := range clips {
+		if math.Abs(clip.SourceStart-wantSourceStarts[i]) > 1e-9 || math.Abs(clip.Start-wantStarts[i]) > 1e-9 {
+			t.Errorf("audioTimeline()[%d] starts at %v in %s and %v in the output, want %v and %v",
+				i, clip.SourceStart, clip.Source, clip.Start, wantSourceStarts[i], wantStarts[i])
+		}
+	}
+
+	// Interleaved narrations each continue from where they stopped
+	s = slideshow{audios: []string{"a.mp3", "b.mp3", "a.mp3"}, timings: []string{"2000", "3000", "4000"},
+		transitionDurations: []string{"1000", "1000", "1000"}, transitionSplits: []string{"start", "start", "start"}, mixes: make([]musicMix, 3)}
+	clips = s.audioTimeline(FFmpeg.AudioFades{Crossfade: true})
+	if clips[2].SourceStart != 2 || clips[2].Start != 5 {
+		t.Errorf("interleaved narration starts at %v in %s and %v in the output, want 2 and 5", clips[2].SourceStart, clips[2].Source, clips[2].Start)
+	}
+	if clips[0].Duration != 3 || clips[2].Duration != 4 {
+		t.Errorf("crossfaded clips last %v and %v seconds, want 3 and 4", clips[0].Duration, clips[2].Duration)
+	}
+
+	// Centred transitions crossfade around the start of the slides, a new source cannot start early
+	s.transitionSplits = []string{"centre", "centre", "centre"}
+	clips = s.audioTimeline(FFmpeg.AudioFades{Crossfade: true})
+	want := []FFmpeg.AudioClip{
+		{Source: "a.mp3", SourceStart: 0, Start: 0, Duration: 2.5},
+		{Source: "b.mp3", SourceStart: 0, Start: 2, Duration: 3.5},
+		{Source: "a.mp3", SourceStart: 1.5, Start: 4.5, Duration: 4.5},
+	}
+	for i := range want {
+		if clips[i] != want[i] {
+			t.Errorf("centred crossfade clip %d = %+v, want %+v", i, clips[i], want[i])
+		}
+	}
+}
+
+func TestRewriteSlides(t *testing.T) {
+	template := `<?xml version="1.0" encoding="utf-8"?>
+<slideshow>
+  <!-- The Word -->
+  <slide>
+    <image>title.jpg</image>
+    <timing duration="5000"/>
+  </slide>
+  <slide>
+    <image>1v1 &amp; 2.jpg</image>
+    <narration start="JHN.1.1"/>
+  </slide>
+</slideshow>
+`
+	want := `<?xml version="1.0" encoding="utf-8"?>
+<slideshow>
+  <!-- The Word -->
+  <slide>
+    <image>title.jpg</image>
+    <timing duration="5000"/>
+  </slide>
+  <slide>
+    <audio>
+      <filename>narration-001.mp3</filename>
+    </audio>
+    <image>1v1 &amp; 2.jpg</image>
+    <narration start="JHN.1.1"/>
+    <timing duration="9400"/>
+  </slide>
+</slideshow>
+`
+	got, err := rewriteSlides([]byte(template), []slideChanges{{}, {"narration-001.mp3", "9400", "", ""}})
+	if err != nil || string(got) != want {
+		t.Errorf("rewriteSlides() = %s, %v, want %s", got, err, want)
+	}
+
+	// An existing timing is changed in place
+	retimed := strings.Replace(template, `duration="5000"`, `duration="4000"`, 1)
+	got, err = rewriteSlides([]byte(template), []slideChanges{{"", "4000", "", ""}})
+	if err != nil || string(got) != retimed {
+		t.Errorf("rewriteSlides() = %s, %v, want %s", got, err, retimed)
+	}
+}
+
+func TestCompareTimings(t *testing.T) {
+	logSlides := []Timing.LogSlide{
+		{Number: 1, StartTime: -1, EndTime: -1, Duration: 5},
+		{Number: 2, StartRef: "JHN.1.1", StartTime: 4.36, EndTime: -1, Duration: 9.4},
+		{Number: 3, StartRef: "JHN.1.3", EndRef: "JHN.1.3", StartTime: 13.76, EndTime: 19.72, Duration: 5.96},
+	}
+	slides := []slide{
+		{Timing: timing{Duration: "5000"}},
+		{Narration: narration{Start: "JHN.1.1"}, Timing: timing{Duration: "9000"}},
+		{Narration: narration{Start: "JHN.1.3", End: "JHN.1.4"}, Timing: timing{Duration: "5960"}},
+	}
+	want := []string{
+		`slide 2: <timing duration> is "9000", the log has 9400 ms`,
+		`slide 3: <narration end> is "JHN.1.4", the log has "JHN.1.3"`,
+	}
+	if got := compareTimings(slides, logSlides); !reflect.DeepEqual(got, want) {
+		t.Errorf("compareTimings() = %q, want %q", got, want)
+	}
+	if got := compareTimings(slides[:2], logSlides); len(got) != 2 {
+		t.Errorf("compareTimings() with a missing slide = %q, want the slide count and the duration", got)
+	}
+}
+
+func TestExtractSpan(t *testing.T) {
+	labels := path.Join(t.TempDir(), "labels.txt")
+	err := os.WriteFile(labels, []byte("4.36\t4.36\t1\n13.76\t13.76\t3\n19.72\t19.72\t4\n23.92\t23.92\t5a\n26.2\t26.2\t5b\n28.48\t28.48\t6\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		extract    audioExtract
+		start, end float64
+	}{
+		{"clock time to the end of the audio", audioExtract{"chapter.mp3", "00:00:04.360", "", "", ""}, 4.36, 116.32},
+		{"seconds", audioExtract{"chapter.mp3", "4.36", "116.32", "", ""}, 4.36, 116.32},
+		{"verse references", audioExtract{"chapter.mp3", "JHN.1.3", "JHN.1.5", labels, ""}, 13.76, 28.48},
+		{"time and verse reference", audioExtract{"chapter.mp3", "", "JHN.1.4", labels, ""}, 0, 23.92},
+		{"references without the book are times", audioExtract{"chapter.mp3", "5", "1:5", labels, ""}, 5, 65},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := tt.extract.span(func() float64 { return 116.32 })
+			if err != nil || math.Abs(start-tt.start) > 1e-9 || math.Abs(end-tt.end) > 1e-9 {
+				t.Errorf("span() = %v, %v, %v, want %v, %v", start, end, err, tt.start, tt.end)
+			}
+		})
+	}
+
+	if _, _, err := (audioExtract{"chapter.mp3", "JHN.1.3", "", "", ""}).span(func() float64 { return 116.32 }); err == nil {
+		t.Errorf("span() with a verse reference and no labels should fail")
+	}
+	if _, _, err := (audioExtract{"chapter.mp3", "5a", "", labels, ""}).span(func() float64 { return 116.32 }); err == nil {
+		t.Errorf("span() with a verse without the book should fail")
+	}
+
+	extracts := []audioExtract{}
+	first := addExtract(&extracts, audioExtract{"chapter.mp3", "4.36", "", "", ""}, "temp")
+	second := addExtract(&extracts, audioExtract{"chapter.mp3", "4.36", "", "", ""}, "temp")
+	if first != "temp/narration-01.wav" || second != first || len(extracts) != 1 {
+		t.Errorf("addExtract() = %s, %s with %d extracts, want the same segment once", first, second, len(extracts))
+	}
+}
+
+func TestNaturalLess(t *testing.T) {
+	names := []string{"img10.jpg", "Img2.jpg", "img1.jpg", "cover.jpg", "img02b.jpg", "img002a.jpg"}
+	sort.SliceStable(names, func(i, j int) bool { return naturalLess(names[i], names[j]) })
+	want := []string{"cover.jpg", "img1.jpg", "Img2.jpg", "img002a.jpg", "img02b.jpg", "img10.jpg"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("naturalLess() sorted %v, want %v", names, want)
+	}
+}
+
+func TestInit(t *testing.T) {
+	directory := path.Join(t.TempDir(), "Story & Co")
+	if err := os.Mkdir(directory, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"2.jpg", "10.png", "notes.txt"} {
+		if err := os.WriteFile(path.Join(directory, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	output := path.Join(directory, "story.slideshow")
+	if err := Init(directory, "", 4000, output, false); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	template := readSlideshowXML(output)
+	if len(template.Title) != 1 || template.Title[0].Name != "Story & Co" {
+		t.Errorf("Init() title = %v, want Story & Co", template.Title)
+	}
+	if len(template.Slide) != 2 || template.Slide[0].Image[0].Name != "2.jpg" || template.Slide[1].Image[0].Name != "10.png" {
+		t.Fatalf("Init() slides = %v, want 2.jpg then 10.png", template.Slide)
+	}
+	for i, slide := range template.Slide {
+		if slide.Timing.Duration != "4000" || slide.Motion.Start == "" || slide.Audio.Filename.Name != "" {
+			t.Errorf("Init() slide %d = %v, want a 4000 ms slide with a motion and no audio", i+1, slide)
+		}
+	}
+	if template.Slide[0].Transition.Type != "fade" || template.Slide[1].Transition.Type != "" {
+		t.Errorf("Init() transitions = %q, %q, want a fade between the slides", template.Slide[0].Transition.Type, template.Slide[1].Transition.Type)
+	}
+
+	if err := Init(directory, "", 4000, output, false); err == nil {
+		t.Errorf("Init() over an existing .slideshow should fail")
+	}
+}
+
+func TestRenderCards(t *testing.T) {
+	s := slideshow{images: []string{"Jn01.1-18-title.jpg"}, title: "The Word", tempPath: t.TempDir()}
+	for _, layout := range []string{"credits", "upper-third"} {
+		if err := s.RenderCards(nil, "", layout, "", false); err == nil {
+			t.Errorf("RenderCards() with the title layout %q should fail", layout)
+		}
+	}
+	if err := s.RenderCards(nil, "", "lower-third", "", false); err != nil || path.Base(s.images[0]) != "card-0.jpg" {
+		t.Errorf("RenderCards() = %v with the title slide %s, want card-0.jpg", err, s.images[0])
+	}
+}
+
+func TestCreateContactSheet(t *testing.T) {
+	directory := t.TempDir()
+	slidePNG := path.Join(directory, "slide.png")
+	file, err := os.Create(slidePNG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(file, Image.NewRGBA(Image.Rect(0, 0, 64, 36))); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	s := slideshow{images: []string{"../../TestInput/VB-John 1v1.jpg", slidePNG}}
+	output := path.Join(directory, "contact.jpg")
+	if err := s.CreateContactSheet(2, output); err != nil {
+		t.Fatalf("CreateContactSheet() with a PNG slide error = %v", err)
+	}
+	if _, err := os.Stat(output); err != nil {
+		t.Errorf("CreateContactSheet() did not write the contact sheet: %v", err)
+	}
+}
+
+func TestGenerateMotions(t *testing.T) {
+	s := NewSlideshow("../../TestInput/test.slideshow", "", false, "../../TestInput")
+	want := []bool{false, false, false, false, true, false, false, false}
+	if !reflect.DeepEqual(s.autoMotions, want) {
+		t.Fatalf("autoMotions = %v, want %v", s.autoMotions, want)
+	}
+
+	if err := s.GenerateMotions("zoomin", 1, false, false); err != nil {
+		t.Fatalf("GenerateMotions() error = %v", err)
+	}
+	for i, auto := range want {
+		moving := !reflect.DeepEqual(s.motions[i][0], s.motions[i][1])
+		if auto && !moving {
+			t.Errorf("GenerateMotions() slide %d = %v, want a motion", i+1, s.motions[i])
+		}
+	}
+	if !reflect.DeepEqual(s.motions[0], [][]float64{{0, 0, 1, 1}, {0, 0, 1, 1}}) {
+		t.Errorf("GenerateMotions() changed the title slide to %v", s.motions[0])
+	}
+}
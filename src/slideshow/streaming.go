@@ -0,0 +1,59 @@
+package slideshow
+
+import (
+	"os"
+	"strings"
+
+	FFmpeg "github.com/sillsdev/appbuilder-storybuilder/src/ffmpeg"
+)
+
+/* Function to find the WebVTT subtitles made by SAB for the slideshow, which are stored next
+ * to the .slideshow file with the same name
+ *
+ * Returns:
+ *		path to the .vtt file, empty if there is none
+ */
+func (s slideshow) SubtitlesFile() string {
+	subtitles := s.templateDirectory + strings.TrimSuffix(s.templateName, ".slideshow") + ".vtt"
+	if _, err := os.Stat(subtitles); err != nil {
+		return ""
+	}
+	return subtitles
+}
+
+/* Function to create HLS and/or DASH adaptive streaming output from the final video
+ *
+ * Parameters:
+ *		finalVideo - path to the final video
+ *		subtitles - path to a WebVTT file for the subtitle rendition, SubtitlesFile() is used if empty
+ *		heights - pixel height of each rendition, heights above the rendered resolution are skipped
+ *		lowQuality - whether the video was rendered at the lower resolution
+ *		hls - whether to create an HLS ladder
+ *		dash - whether to create a DASH manifest
+ *		outputBase - path and filename without extension to save the streaming folders to
+ */
+func (s slideshow) CreateStreams(finalVideo string, subtitles string, heights []int, lowQuality bool, hls bool, dash bool, outputBase string) {
+	maxHeight := 720
+	if lowQuality {
+		maxHeight = 480
+	}
+	renditions := []int{}
+	for _, height := range heights {
+		if height <= maxHeight {
+			renditions = append(renditions, height)
+		}
+	}
+	if len(renditions) == 0 {
+		renditions = append(renditions, maxHeight)
+	}
+
+	if subtitles == "" {
+		subtitles = s.SubtitlesFile()
+	}
+	lang := s.lang
+	if code, ok := iso639_2[strings.ToLower(lang)]; ok {
+		lang = code
+	}
+
+	FFmpeg.CreateStreams(finalVideo, subtitles, lang, renditions, hls, dash, outputBase)
+}
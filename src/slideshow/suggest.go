@@ -0,0 +1,68 @@
+package slideshow
+
+import (
+	"fmt"
+	"os"
+
+	FFmpeg "github.com/sillsdev/appbuilder-storybuilder/src/ffmpeg"
+	Timing "github.com/sillsdev/appbuilder-storybuilder/src/timing"
+)
+
+/* Function to suggest slide timings from the pauses of a narration when there are no verse timestamps.
+ * With a template, its narrated slides (the ones without a <timing>) start at the pauses closest to an
+ * even split of the narration and the template is written with the suggested timings for review.
+ * Without a template, slides start at the pauses closest to the target length and the suggested
+ * timings are printed.
+ *
+ * Parameters:
+ *		templatePath - path to the template .slideshow, empty to suggest slides of the target length
+ *		narrationPath - path to the narration audio
+ *		outputPath - path of the .slideshow to write
+ *		target - length in seconds wanted for each slide when there is no template
+ *		noise - level in dB below which the narration counts as silence
+ *		minPause - shortest pause in seconds a slide can start at
+ *		v - verbose flag to determine what feedback to print
+ * Returns:
+ *		err - error in the event of a failure, nil if successful
+ */
+func Suggest(templatePath string, narrationPath string, outputPath string, target float64, noise float64, minPause float64, v bool) error {
+	audioDuration := FFmpeg.GetVideoLength(narrationPath)
+	pauses := []float64{}
+	for _, silence := range FFmpeg.DetectSilences(narrationPath, noise, minPause) {
+		// Slides change in the middle of a pause
+		pauses = append(pauses, (silence.Start+silence.End)/2)
+	}
+	if v {
+		fmt.Printf("Found %d pauses in %.2f seconds of narration\n", len(pauses), audioDuration)
+	}
+
+	if templatePath == "" {
+		times := Timing.SuggestByDuration(pauses, audioDuration, target)
+		for i := 0; i+1 < len(times); i++ {
+			fmt.Printf("Slide %d: %.2f-%.2f <timing duration=\"%s\"/>\n", i+1, times[i], times[i+1], durationMilliseconds(times[i], times[i+1]))
+		}
+		return nil
+	}
+
+	data, err := os.ReadFile(templatePath)
+	if err != nil {
+		return err
+	}
+	template := readSlideshowXML(templatePath)
+
+	narrated := []int{}
+	for i, slide := range template.Slide {
+		if slide.Timing.Duration == "" {
+			narrated = append(narrated, i)
+		}
+	}
+	if len(narrated) == 0 {
+		return fmt.Errorf("the template has no narrated slides")
+	}
+	if len(pauses) < len(narrated)-1 {
+		fmt.Printf("Warning: only %d pauses found for %d narrated slides, the rest of the narration is split evenly\n", len(pauses), len(narrated))
+	}
+
+	times := Timing.SuggestByCount(pauses, audioDuration, len(narrated))
+	return fillTemplate(data, template, narrated, times, narrationPath, audioDuration, outputPath, v)
+}
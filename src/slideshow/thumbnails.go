@@ -0,0 +1,137 @@
+package slideshow
+
+import (
+	"fmt"
+	Image "image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	FFmpeg "github.com/sillsdev/appbuilder-storybuilder/src/ffmpeg"
+	"github.com/sillsdev/appbuilder-storybuilder/src/helper"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Size of each image in the thumbnails and contact sheet
+const thumbnailWidth = 320
+const thumbnailHeight = 180
+
+/* Function to find the time of the poster frame
+ *
+ * Parameters:
+ *		spec - "slide:N" for the middle of slide N (starting at 1), or a time in seconds
+ *		offsets - start time of each slide in seconds followed by the total length
+ * Returns:
+ *		the time of the poster frame in seconds and an error if the spec is invalid
+ */
+func posterTime(spec string, offsets []float64) (float64, error) {
+	if strings.HasPrefix(spec, "slide:") {
+		slide, err := strconv.Atoi(strings.TrimPrefix(spec, "slide:"))
+		if err != nil || slide < 1 || slide >= len(offsets) {
+			return 0, fmt.Errorf("invalid poster slide %q, expected slide:1 to slide:%d", spec, len(offsets)-1)
+		}
+		return (offsets[slide-1] + offsets[slide]) / 2, nil
+	}
+
+	seconds, err := strconv.ParseFloat(spec, 64)
+	if err != nil || seconds < 0 || seconds > offsets[len(offsets)-1] {
+		return 0, fmt.Errorf("invalid poster time %q, expected slide:N or seconds within the video", spec)
+	}
+	return seconds, nil
+}
+
+/* Function to export a poster frame from the rendered video
+ *
+ * Parameters:
+ *		spec - "slide:N" for the middle of slide N (starting at 1), or a time in seconds
+ *		offsets - start time of each slide in seconds followed by the total length (see CreateVideo)
+ *		finalVideo - path to the rendered video
+ *		outputPath - path of the poster JPEG
+ * Returns:
+ *		err - error in the event of a failure, nil if successful
+ */
+func (s slideshow) CreatePoster(spec string, offsets []float64, finalVideo string, outputPath string) error {
+	seconds, err := posterTime(spec, offsets)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Exporting poster frame at %.2f seconds to %s...\n", seconds, outputPath)
+	cmd := FFmpeg.CmdExtractFrame(finalVideo, seconds, outputPath)
+	output, err := cmd.CombinedOutput()
+	FFmpeg.CheckCMDError(output, err)
+	return nil
+}
+
+/* Function to export a thumbnail of each slide from the scaled slide images
+ *
+ * Parameters:
+ *		outputDirectory - folder to store the thumbnails in
+ * Returns:
+ *		err - error in the event of a failure, nil if successful
+ */
+func (s slideshow) CreateThumbnails(outputDirectory string) error {
+	if err := os.MkdirAll(outputDirectory, os.ModePerm); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exporting slide thumbnails to %s...\n", outputDirectory)
+	for i, image := range s.images {
+		cmd := FFmpeg.CmdScaleImage(image, strconv.Itoa(thumbnailHeight), strconv.Itoa(thumbnailWidth), path.Join(outputDirectory, fmt.Sprintf("slide-%02d.jpg", i+1)))
+		output, err := cmd.CombinedOutput()
+		FFmpeg.CheckCMDError(output, err)
+	}
+	return nil
+}
+
+/* Function to create a contact sheet, a grid of all the slides labelled with their number,
+ * from the scaled slide images for reviewing a slideshow at a glance
+ *
+ * Parameters:
+ *		columns - number of slides in each row
+ *		outputPath - path of the contact sheet JPEG
+ * Returns:
+ *		err - error in the event of a failure, nil if successful
+ */
+func (s slideshow) CreateContactSheet(columns int, outputPath string) error {
+	const gap = 8
+	rows := (len(s.images) + columns - 1) / columns
+	sheet := Image.NewRGBA(Image.Rect(0, 0, columns*(thumbnailWidth+gap)+gap, rows*(thumbnailHeight+gap)+gap))
+	draw.Draw(sheet, sheet.Bounds(), &Image.Uniform{color.RGBA{32, 32, 32, 255}}, Image.Point{}, draw.Src)
+
+	fmt.Printf("Creating contact sheet %s...\n", outputPath)
+	for i, name := range s.images {
+		img, err := helper.ReadImage(name)
+		if err != nil {
+			return err
+		}
+
+		x := gap + (i%columns)*(thumbnailWidth+gap)
+		y := gap + (i/columns)*(thumbnailHeight+gap)
+		tile := Image.Rect(x, y, x+thumbnailWidth, y+thumbnailHeight)
+		draw.ApproxBiLinear.Scale(sheet, tile, img, img.Bounds(), draw.Src, nil)
+
+		label := &font.Drawer{
+			Dst:  sheet,
+			Src:  Image.NewUniform(color.White),
+			Face: basicfont.Face7x13,
+			Dot:  fixed.P(x+6, y+thumbnailHeight-6),
+		}
+		draw.Draw(sheet, Image.Rect(x, y+thumbnailHeight-20, x+30, y+thumbnailHeight), &Image.Uniform{color.RGBA{0, 0, 0, 160}}, Image.Point{}, draw.Over)
+		label.DrawString(strconv.Itoa(i + 1))
+	}
+
+	fd, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	return jpeg.Encode(fd, sheet, &jpeg.Options{Quality: 90})
+}
@@ -0,0 +1,125 @@
+package slideshow
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	FFmpeg "github.com/sillsdev/appbuilder-storybuilder/src/ffmpeg"
+)
+
+/* Structure of a slide in the JSON timeline
+ *	Index: position of the slide in the slideshow, starting at 1
+ *	Image: image filename relative to the .slideshow
+ *	Start: time in seconds the slide starts
+ *	End: time in seconds the slide ends
+ *	Narration: verse reference where the narration of the slide starts, if specified
+ */
+type timelineSlide struct {
+	Index     int     `json:"index"`
+	Image     string  `json:"image"`
+	Start     float64 `json:"start"`
+	End       float64 `json:"end"`
+	Narration string  `json:"narration,omitempty"`
+}
+
+/* Structure of the JSON timeline that lets apps show the slides in sync with the audio
+ *	Title: title of the story
+ *	Lang: language of the slideshow
+ *	Audio: filename of the audio the timeline belongs to
+ *	Duration: total length in seconds
+ *	Slides: the slides in order
+ */
+type timeline struct {
+	Title    string          `json:"title,omitempty"`
+	Lang     string          `json:"lang,omitempty"`
+	Audio    string          `json:"audio"`
+	Duration float64         `json:"duration"`
+	Slides   []timelineSlide `json:"slides"`
+}
+
+/* Function to get the duration of each slide in seconds
+ *
+ * Returns:
+ *		the durations, slides without a timing last 5 seconds like in the video
+ */
+func (s slideshow) timingSeconds() []float64 {
+	durations := []float64{}
+	for _, timing := range s.timings {
+		duration, err := strconv.ParseFloat(strings.TrimSpace(timing), 64)
+		if err != nil {
+			duration = 5000
+		}
+		durations = append(durations, duration/1000)
+	}
+	return durations
+}
+
+/* Function to create the timeline of the slides
+ *
+ * Parameters:
+ *		audio - filename of the audio the timeline belongs to
+ * Returns:
+ *		the timeline
+ */
+func (s slideshow) timeline(audio string) timeline {
+	offsets := FFmpeg.SlideOffsets(s.timingSeconds())
+	return timeline{s.title, s.lang, audio, offsets[len(offsets)-1], s.timelineSlides(offsets)}
+}
+
+/* Function to list when each slide starts and ends
+ *
+ * Parameters:
+ *		offsets - start time of each slide in seconds followed by the total length
+ * Returns:
+ *		the slides in order
+ */
+func (s slideshow) timelineSlides(offsets []float64) []timelineSlide {
+	slides := []timelineSlide{}
+	for i, image := range s.images {
+		slides = append(slides, timelineSlide{
+			Index:     i + 1,
+			Image:     strings.TrimPrefix(image, s.templateDirectory),
+			Start:     offsets[i],
+			End:       offsets[i+1],
+			Narration: s.narrations[i],
+		})
+	}
+	return slides
+}
+
+/* Function to export only the audio of the slideshow (narration mixed with the background music)
+ * along with a JSON timeline listing when each slide starts and ends
+ *
+ * Parameters:
+ *		format - "mp3", "m4a" or "opus"
+ *		fades - fades of the background music and at the cuts and transitions of the audio
+ *		loudness - EBU R128 target to normalize the mix to, nil to keep the mix as is
+ *		outputBase - path and filename without extension to save the audio and timeline to
+ *		v - verbose flag to determine what feedback to print
+ * Returns:
+ *		err - error in the event of a failure, nil if successful
+ */
+func (s slideshow) ExportAudioOnly(format string, fades FFmpeg.AudioFades, loudness *FFmpeg.LoudnessTarget, outputBase string, v bool) error {
+	if !FFmpeg.IsAudioFormat(format) {
+		return fmt.Errorf("unsupported audio format %q, expected mp3, m4a or opus", format)
+	}
+
+	audioFile := outputBase + "." + format
+	t := s.timeline(path.Base(audioFile))
+	mix := FFmpeg.MixAudio(s.audioTimeline(fades), s.tempPath, v)
+	if loudness != nil {
+		FFmpeg.NormalizeLoudness(mix, *loudness, v)
+	}
+	FFmpeg.ExportAudio(mix, format, t.Duration, audioFile)
+
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Writing timeline to %s-timeline.json...\n", outputBase)
+	return os.WriteFile(outputBase+"-timeline.json", data, 0644)
+}
@@ -8,13 +8,21 @@ import (
 )
 
 type slideshow_template struct {
+	Title []title `xml:"title"`
 	Slide []slide `xml:"slide"`
 }
 
+type title struct {
+	Lang string `xml:"lang,attr"`
+	Name string `xml:",chardata"`
+}
+
 type slide struct {
 	Audio      audio      `xml:"audio"`
-	Image      image      `xml:"image"`
+	Image      []image    `xml:"image"`
 	Motion     motion     `xml:"motion"`
+	Narration  narration  `xml:"narration"`
+	Chapter    string     `xml:"chapter"`
 	Timing     timing     `xml:"timing"`
 	Transition transition `xml:"transition"`
 }
@@ -31,14 +39,25 @@ type background_Filename struct {
 }
 
 type filename struct {
-	Name string `xml:",chardata"`
+	Start  string `xml:"start,attr"`
+	End    string `xml:"end,attr"`
+	Labels string `xml:"labels,attr"`
+	Name   string `xml:",chardata"`
 }
 
 type image struct {
+	Lang string `xml:"lang,attr"`
 	Name string `xml:",chardata"`
 }
 
 type motion struct {
+	Start  string `xml:"start,attr"`
+	End    string `xml:"end,attr"`
+	Easing string `xml:"easing,attr"`
+	Fit    string `xml:"fit,attr"`
+}
+
+type narration struct {
 	Start string `xml:"start,attr"`
 	End   string `xml:"end,attr"`
 }
@@ -51,6 +70,8 @@ type timing struct {
 
 type transition struct {
 	Duration string `xml:"duration,attr"`
+	TypeAttr string `xml:"type,attr"`
+	Split    string `xml:"split,attr"`
 	Type     string `xml:",chardata"`
 }
 
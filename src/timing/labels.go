@@ -0,0 +1,85 @@
+package timing
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/* Structure of a verse timestamp
+ *	Start: time in seconds the verse (or phrase of a verse) starts in the narration
+ *	End: time in seconds it ends, the same as Start for point labels
+ *	Name: the label, e.g. "JHN.1.5", "1:5", "5" or "5b"
+ */
+type Label struct {
+	Start float64
+	End   float64
+	Name  string
+}
+
+/* Function to read a verse timestamp file, either an Audacity label track or a SAB timing file.
+ * Both have a label per line with tab separated start time, end time and name.
+ *
+ * Parameters:
+ *		filePath - path to the timestamp file
+ * Returns:
+ *		the labels in order and an error if the file cannot be read
+ */
+func ReadLabels(filePath string) ([]Label, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	labels := []Label{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "\ufeff")
+		// Audacity writes spectral selections as "\" lines, SAB timing files may have comments
+		if text == "" || strings.HasPrefix(text, "\\") || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("%s:%d: expected start, end and label separated by tabs", filePath, line)
+		}
+		start, err := strconv.ParseFloat(strings.TrimSpace(fields[0]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid start time %q", filePath, line, fields[0])
+		}
+		end, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid end time %q", filePath, line, fields[1])
+		}
+		labels = append(labels, Label{start, end, strings.TrimSpace(fields[2])})
+	}
+	return labels, scanner.Err()
+}
+
+/* Function to find the verse a label or reference is for
+ *
+ * Parameters:
+ *		name - a label like "JHN.1.5", "1:5", "v5", "5" or "5b", or a reference like "JHN.1.5"
+ * Returns:
+ *		the verse number and the phrase letter (empty if none), the verse is -1 if there is none
+ */
+func verse(name string) (int, string) {
+	if i := strings.LastIndexAny(name, ".: "); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimPrefix(strings.ToLower(name), "v")
+
+	digits := 0
+	for digits < len(name) && name[digits] >= '0' && name[digits] <= '9' {
+		digits++
+	}
+	number, err := strconv.Atoi(name[:digits])
+	if err != nil {
+		return -1, ""
+	}
+	return number, name[digits:]
+}
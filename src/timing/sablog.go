@@ -0,0 +1,86 @@
+package timing
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/* Structure of a slide as recorded in the log written by Scripture App Builder when it produces a slideshow
+ *	Number: the slide number, starting at 1
+ *	StartRef: the "Start Ref" verse reference, empty if the slide continues the previous slide's verse
+ *	EndRef: the "End Ref" verse reference, empty if not recorded
+ *	StartTime: the "Start Time" in seconds in the narration, -1 if the slide has no narration
+ *	EndTime: the "End Time" in seconds in the narration, -1 if not recorded
+ *	Duration: the "Duration" of the slide in seconds
+ */
+type LogSlide struct {
+	Number    int
+	StartRef  string
+	EndRef    string
+	StartTime float64
+	EndTime   float64
+	Duration  float64
+}
+
+/* Function to read the slides of a SAB log (sab.log)
+ *
+ * Parameters:
+ *		filePath - path to the log
+ * Returns:
+ *		the slides in order and an error if the log cannot be read
+ */
+func ReadSABLog(filePath string) ([]LogSlide, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	slides := []LogSlide{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(text, "Slide ") {
+			number := strings.TrimPrefix(text, "Slide ")
+			n, err := strconv.Atoi(number)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid slide number %q", filePath, line, number)
+			}
+			slides = append(slides, LogSlide{Number: n, StartTime: -1, EndTime: -1})
+			continue
+		}
+
+		// Lines before the first slide describe the slideshow
+		colon := strings.Index(text, ":")
+		if colon < 0 || len(slides) == 0 {
+			continue
+		}
+		slide := &slides[len(slides)-1]
+		key, value := text[:colon], strings.TrimSpace(text[colon+1:])
+
+		switch key {
+		case "Start Ref":
+			slide.StartRef = value
+		case "End Ref":
+			slide.EndRef = value
+		case "Start Time", "End Time", "Duration":
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid %s %q", filePath, line, strings.ToLower(key), value)
+			}
+			switch key {
+			case "Start Time":
+				slide.StartTime = seconds
+			case "End Time":
+				slide.EndTime = seconds
+			default:
+				slide.Duration = seconds
+			}
+		}
+	}
+	return slides, scanner.Err()
+}
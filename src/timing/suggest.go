@@ -0,0 +1,77 @@
+package timing
+
+/* Function to suggest slide boundaries in a narration without verse timestamps, at the pauses closest
+ * to an even split of the narration between the slides
+ *
+ * Parameters:
+ *		pauses - times in seconds of the pauses in the narration, in order
+ *		audioDuration - length of the narration in seconds
+ *		count - number of slides
+ * Returns:
+ *		the start time in seconds of each slide followed by the end of the last slide
+ */
+func SuggestByCount(pauses []float64, audioDuration float64, count int) []float64 {
+	times := make([]float64, count+1)
+	times[count] = audioDuration
+	candidates := inside(pauses, audioDuration)
+
+	for k := 1; k < count; k++ {
+		used := times[k-1]
+		// Without a pause for this slide and each of the following ones, the rest of the narration is split evenly
+		times[k] = used + (audioDuration-used)/float64(count-k+1)
+		if len(candidates) >= count-k {
+			candidate, _ := nearest(candidates, float64(k)*audioDuration/float64(count), used)
+			times[k] = candidate
+		}
+		for len(candidates) > 0 && candidates[0] <= times[k] {
+			candidates = candidates[1:]
+		}
+	}
+	return times
+}
+
+/* Function to suggest slide boundaries in a narration without verse timestamps, at the pauses closest
+ * to a target length of each slide
+ *
+ * Parameters:
+ *		pauses - times in seconds of the pauses in the narration, in order
+ *		audioDuration - length of the narration in seconds
+ *		target - length in seconds wanted for each slide
+ * Returns:
+ *		the start time in seconds of each slide followed by the end of the last slide
+ */
+func SuggestByDuration(pauses []float64, audioDuration float64, target float64) []float64 {
+	times := []float64{0}
+	candidates := inside(pauses, audioDuration)
+
+	// A slide is only started if what is left of the narration is longer than one and a half slides
+	for last := 0.0; audioDuration-last > target*1.5; {
+		want := last + target
+		// Slides are at least half the target length
+		cut, found := nearest(candidates, want, last+target/2)
+		if !found || cut > audioDuration-target/2 {
+			cut = want
+		}
+		times = append(times, cut)
+		last = cut
+	}
+	return append(times, audioDuration)
+}
+
+/* Function to keep the pauses a slide can start at
+ *
+ * Parameters:
+ *		pauses - times in seconds of the pauses in the narration, in order
+ *		audioDuration - length of the narration in seconds
+ * Returns:
+ *		the pauses after the start and before the end of the narration
+ */
+func inside(pauses []float64, audioDuration float64) []float64 {
+	candidates := []float64{}
+	for _, pause := range pauses {
+		if pause > 0 && pause < audioDuration {
+			candidates = append(candidates, pause)
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,217 @@
+package timing
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/* Structure of a narrated slide of a template
+ *	Start: verse reference (e.g. JHN.1.5) the narration of the slide starts at, empty if it continues the previous slide's verse
+ *	End: verse reference the narration of the slide ends at, empty if not specified
+ */
+type Slide struct {
+	Start string
+	End   string
+}
+
+/* Function to find when a verse starts
+ *
+ * Parameters:
+ *		labels - the verse timestamps
+ *		v - the verse number
+ * Returns:
+ *		the earliest start time of the verse's labels and whether the verse has a label
+ */
+func verseStart(labels []Label, v int) (float64, bool) {
+	start, found := 0.0, false
+	for _, label := range labels {
+		if n, _ := verse(label.Name); n == v && (!found || label.Start < start) {
+			start, found = label.Start, true
+		}
+	}
+	return start, found
+}
+
+/* Function to find when a verse ends
+ *
+ * Parameters:
+ *		labels - the verse timestamps
+ *		v - the verse number
+ *		audioDuration - length of the narration in seconds, used if nothing follows the verse
+ * Returns:
+ *		the end of the verse's last region label, or else the start of the next label, or else the audio duration
+ */
+func verseEnd(labels []Label, v int, audioDuration float64) float64 {
+	last := -1
+	for i, label := range labels {
+		if n, _ := verse(label.Name); n == v {
+			last = i
+		}
+	}
+	if last >= 0 && labels[last].End > labels[last].Start {
+		return labels[last].End
+	}
+	if last >= 0 && last+1 < len(labels) {
+		return labels[last+1].Start
+	}
+	return audioDuration
+}
+
+/* Function to compute when each narrated slide starts in the narration from verse timestamps. Slides
+ * continuing the previous slide's verse start at the phrase labels closest to an even split of the
+ * verses, or at the even split if there are not enough phrase labels.
+ *
+ * Parameters:
+ *		slides - the narrated slides in order, the first one must have a start reference
+ *		labels - the verse timestamps
+ *		audioDuration - length of the narration in seconds
+ * Returns:
+ *		the start time in seconds of each slide in the narration followed by the end of the last slide,
+ *		and an error if a reference has no timestamp or the slides are out of order
+ */
+func SlideTimes(slides []Slide, labels []Label, audioDuration float64) ([]float64, error) {
+	if len(slides) == 0 {
+		return nil, fmt.Errorf("the template has no narrated slides")
+	}
+	if slides[0].Start == "" {
+		return nil, fmt.Errorf("the first narrated slide has no <narration start> reference")
+	}
+
+	times := make([]float64, len(slides)+1)
+	lastVerse := -1
+	for i, slide := range slides {
+		if slide.Start == "" {
+			continue
+		}
+		v, _ := verse(slide.Start)
+		start, found := verseStart(labels, v)
+		if !found {
+			return nil, fmt.Errorf("no timestamp for %s", slide.Start)
+		}
+		times[i] = start
+		lastVerse = v
+	}
+
+	if end := slides[len(slides)-1].End; end != "" {
+		lastVerse, _ = verse(end)
+	}
+	times[len(slides)] = verseEnd(labels, lastVerse, audioDuration)
+
+	for first := 0; first < len(slides); {
+		next := first + 1
+		for next < len(slides) && slides[next].Start == "" {
+			next++
+		}
+		splitVerses(times, first, next, labels)
+		first = next
+	}
+
+	for i := 1; i < len(times); i++ {
+		if times[i] <= times[i-1] {
+			return nil, fmt.Errorf("narrated slide %d would have no length, check the timestamps around %s", i, slides[i-1].Start)
+		}
+	}
+	return times, nil
+}
+
+/* Function to place the slides continuing a verse between two slides with start references
+ *
+ * Parameters:
+ *		times - start time of each slide, updated for the slides after first and before next
+ *		first - index of the slide with a start reference
+ *		next - index of the next slide with a start reference, or the end
+ *		labels - the verse timestamps
+ */
+func splitVerses(times []float64, first int, next int, labels []Label) {
+	count := next - first
+	if count == 1 {
+		return
+	}
+
+	candidates := []float64{}
+	for _, label := range labels {
+		if label.Start > times[first] && label.Start < times[next] {
+			candidates = append(candidates, label.Start)
+		}
+	}
+
+	step := (times[next] - times[first]) / float64(count)
+	used := times[first]
+	for k := 1; k < count; k++ {
+		target := times[first] + float64(k)*step
+		times[first+k] = target
+
+		if len(candidates) >= count-1 {
+			if candidate, found := nearest(candidates, target, used); found {
+				times[first+k] = candidate
+			}
+		}
+		used = times[first+k]
+	}
+}
+
+/* Function to find the candidate time closest to a target
+ *
+ * Parameters:
+ *		candidates - the times to choose from
+ *		target - the time wanted
+ *		after - only candidates after this time are considered
+ * Returns:
+ *		the closest candidate and whether there is one
+ */
+func nearest(candidates []float64, target float64, after float64) (float64, bool) {
+	best, found := 0.0, false
+	for _, candidate := range candidates {
+		if candidate > after && (!found || math.Abs(candidate-target) < math.Abs(best-target)) {
+			best, found = candidate, true
+		}
+	}
+	return best, found
+}
+
+/* Function to find the part of a narration covering a range of verses
+ *
+ * Parameters:
+ *		labels - the verse timestamps
+ *		start - verse reference the range starts at
+ *		end - verse reference the range ends at, empty for the end of the narration
+ *		audioDuration - length of the narration in seconds
+ * Returns:
+ *		the start and end time in seconds, and an error if a reference has no timestamp
+ */
+func VerseSpan(labels []Label, start string, end string, audioDuration float64) (float64, float64, error) {
+	v, _ := verse(start)
+	from, found := verseStart(labels, v)
+	if !found {
+		return 0, 0, fmt.Errorf("no timestamp for %s", start)
+	}
+	if end == "" {
+		return from, audioDuration, nil
+	}
+	last, _ := verse(end)
+	if _, found := verseStart(labels, last); !found {
+		return 0, 0, fmt.Errorf("no timestamp for %s", end)
+	}
+	return from, verseEnd(labels, last, audioDuration), nil
+}
+
+/* Function to parse a time in an audio
+ *
+ * Parameters:
+ *		value - seconds (e.g. "4.36") or a clock time (e.g. "00:00:04.360" or "01:56.32")
+ * Returns:
+ *		the time in seconds and an error if the value is not a time
+ */
+func ParseTime(value string) (float64, error) {
+	seconds := 0.0
+	for _, part := range strings.Split(strings.TrimSpace(value), ":") {
+		number, err := strconv.ParseFloat(part, 64)
+		if err != nil || number < 0 {
+			return 0, fmt.Errorf("invalid time %q", value)
+		}
+		seconds = seconds*60 + number
+	}
+	return seconds, nil
+}
@@ -0,0 +1,109 @@
+package timing
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSlideTimes(t *testing.T) {
+	labels := []Label{
+		{0, 0, "1"},
+		{9.4, 9.4, "3"},
+		{15.36, 15.36, "4"},
+		{19.56, 19.56, "5a"},
+		{21.84, 21.84, "5b"},
+		{24.12, 24.12, "6"},
+		{35, 40, "7"},
+	}
+	tests := []struct {
+		name   string
+		slides []Slide
+		want   []float64
+	}{
+		{
+			"slides continuing a verse start at its phrases",
+			[]Slide{{"JHN.1.1", ""}, {"JHN.1.3", ""}, {"JHN.1.4", ""}, {"JHN.1.5", ""}, {"", ""}, {"JHN.1.6", "JHN.1.6"}},
+			[]float64{0, 9.4, 15.36, 19.56, 21.84, 24.12, 35},
+		},
+		{
+			"slides continuing verses start at the label closest to an even split",
+			[]Slide{{"JHN.1.1", ""}, {"", ""}, {"JHN.1.7", ""}},
+			[]float64{0, 19.56, 35, 40},
+		},
+		{
+			"slides continuing a verse without labels split it evenly",
+			[]Slide{{"JHN.1.6", ""}, {"", ""}},
+			[]float64{24.12, 29.56, 35},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SlideTimes(tt.slides, labels, 50)
+			if err != nil {
+				t.Fatalf("SlideTimes() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("SlideTimes() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+					t.Errorf("SlideTimes() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+
+	if _, err := SlideTimes([]Slide{{"JHN.1.2", ""}}, labels, 50); err == nil {
+		t.Errorf("SlideTimes() with a verse without timestamp should fail")
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	pauses := []float64{0.4, 4.2, 7.5, 10.8, 16.1, 19.9}
+	tests := []struct {
+		name string
+		got  []float64
+		want []float64
+	}{
+		{"slides start at the pauses closest to an even split", SuggestByCount(pauses, 24, 3), []float64{0, 7.5, 16.1, 24}},
+		{"slides without enough pauses split the narration evenly", SuggestByCount([]float64{3}, 24, 4), []float64{0, 6, 12, 18, 24}},
+		{"slides start at the pauses closest to the target length", SuggestByDuration(pauses, 24, 8), []float64{0, 7.5, 16.1, 24}},
+		{"slides without pauses have the target length", SuggestByDuration(nil, 20, 8), []float64{0, 8, 20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", tt.got, tt.want)
+			}
+			for i := range tt.got {
+				if math.Abs(tt.got[i]-tt.want[i]) > 1e-9 {
+					t.Errorf("got %v, want %v", tt.got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestReadSABLog(t *testing.T) {
+	slides, err := ReadSABLog("../../SampleInput/SAB Video Production v4/[eng] World English Bible/Jn01.01-18 The Word/sab.log")
+	if err != nil {
+		t.Fatalf("ReadSABLog() error = %v", err)
+	}
+	if len(slides) != 17 {
+		t.Fatalf("ReadSABLog() read %d slides, want 17", len(slides))
+	}
+	want := map[int]LogSlide{
+		0:  {1, "", "", -1, -1, 5},
+		1:  {2, "JHN.1.1", "", 4.36, -1, 9.4},
+		5:  {6, "", "", 26.2, -1, 2.28},
+		15: {16, "JHN.1.18", "JHN.1.18", 107.96, 116.32, 8.36},
+	}
+	for i, slide := range want {
+		if !reflect.DeepEqual(slides[i], slide) {
+			t.Errorf("ReadSABLog()[%d] = %v, want %v", i, slides[i], slide)
+		}
+	}
+}